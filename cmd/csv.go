@@ -0,0 +1,279 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+	"github.com/djlechuck/fvtt-packs/internal/sheet"
+	"github.com/spf13/cobra"
+)
+
+// csvIdColumn is the column of the CSV files identifying the documents.
+const csvIdColumn = "_id"
+
+// csvDefaultColumns are the columns of the CSV files preceding the selected ones.
+var csvDefaultColumns = []string{csvIdColumn, "name", "img", "folder"}
+
+// exportCsvCmd represents the export-csv command
+var exportCsvCmd = &cobra.Command{
+	Use:   "export-csv <pack> <file>",
+	Short: "Export the documents of a pack into a CSV file",
+	Long: `Export the documents of a pack into a CSV file, to edit them in a spreadsheet, one row per document. The rows
+hold the _id, name, img and folder of the documents, followed by the fields given with the -c flag, as dotted paths.
+Objects and lists are written as JSON. The -t flag only exports the documents of a given type.
+
+For example:
+
+fvtt-packs export-csv weapons weapons.csv -t weapon -c system.price.value -c system.damage.parts
+	Export the price and damage of the weapons. Once edited, import them back with import-csv.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pack, err := findPack(cmd, args[0])
+		if err != nil {
+			return err
+		}
+
+		docType, _ := cmd.Flags().GetString("type")
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+		header := append(append([]string{}, csvDefaultColumns...), columns...)
+
+		var rows [][]string
+		err = eachPackEntry(pack.path, func(key string, collection string, id string, v interface{}) error {
+			if !isPackDocument(pack, collection) {
+				return nil
+			}
+			if docType != "" {
+				if t, _ := docpath.Get(v, "type"); t != docType {
+					return nil
+				}
+			}
+
+			row := make([]string, len(header))
+			for i, column := range header {
+				value, _ := docpath.Get(v, column)
+				row[i] = sheet.Cell(value)
+			}
+			rows = append(rows, row)
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Sort by name, then by id, as in Foundry.
+		sort.SliceStable(rows, func(i, j int) bool {
+			if rows[i][1] != rows[j][1] {
+				return rows[i][1] < rows[j][1]
+			}
+			return rows[i][0] < rows[j][0]
+		})
+
+		f, err := os.Create(args[1])
+		if err != nil {
+			return fmt.Errorf("cannot create %s: %s\n", args[1], err)
+		}
+		defer f.Close()
+
+		w := csv.NewWriter(f)
+		if err := w.Write(header); err != nil {
+			return err
+		}
+		if err := w.WriteAll(rows); err != nil {
+			return fmt.Errorf("cannot write %s: %s\n", args[1], err)
+		}
+
+		fmt.Println(len(rows), "documents exported into", args[1])
+
+		return nil
+	},
+}
+
+// importCsvCmd represents the import-csv command
+var importCsvCmd = &cobra.Command{
+	Use:   "import-csv <pack> <file>",
+	Short: "Update the documents of a pack from a CSV file",
+	Long: `Update the documents of a pack from a CSV file, as written by export-csv. The documents are matched by the
+_id column, and each other column is a field given as a dotted path. The fields keep their type: cells of number
+fields must be numbers, cells of object and list fields must be JSON. Rows whose cells have not changed are left
+untouched.
+
+Use the --dry-run flag to only report the changes.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pack, err := findPack(cmd, args[0])
+		if err != nil {
+			return err
+		}
+
+		header, rows, err := readCsv(args[1])
+		if err != nil {
+			return err
+		}
+
+		// The first pass checks every cell and reports the changes, so that nothing is written if a cell is invalid.
+		var problems []error
+		matched := map[string]bool{}
+		if _, err := rewritePackEntries(pack.path, true, csvApplier(pack, header, rows, matched, &problems, true)); err != nil {
+			return err
+		}
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%d invalid cells found\n", len(problems))
+		}
+		for id := range rows {
+			if !matched[id] {
+				fmt.Printf("warning: no document %s in pack %s\n", id, pack.name)
+			}
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if dryRun {
+			return nil
+		}
+
+		count, err := rewritePackEntries(pack.path, false, csvApplier(pack, header, rows, matched, &problems, false))
+		if err != nil {
+			return err
+		}
+		fmt.Println(pack.name, ":", count, "entries updated")
+
+		return nil
+	},
+}
+
+// csvApplier returns the function updating the pack entries from the rows of a CSV file, for rewritePackEntries. The
+// ids of the documents found are added to matched, and the invalid cells to problems. With report, the changes are
+// printed.
+func csvApplier(pack packInfo, header []string, rows map[string][]string, matched map[string]bool, problems *[]error, report bool) func(key string, v interface{}) bool {
+	return func(key string, v interface{}) bool {
+		parts := strings.Split(key, "!")
+		id := parts[len(parts)-1]
+		row, ok := rows[id]
+		if !ok || !isPackDocument(pack, parts[1]) {
+			return false
+		}
+		doc, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		matched[id] = true
+
+		changed := false
+		for i, column := range header {
+			if column == csvIdColumn || i >= len(row) {
+				continue
+			}
+			previous, _ := docpath.Get(doc, column)
+			if row[i] == sheet.Cell(previous) {
+				continue
+			}
+
+			value, err := sheet.Parse(row[i], previous)
+			if err == nil && !docpath.Set(doc, column, value) {
+				err = errors.New("the field cannot be set")
+			}
+			if err != nil {
+				*problems = append(*problems, fmt.Errorf("%s %s: %s", key, column, err))
+				continue
+			}
+			if report {
+				fmt.Printf("%s %s: %s -> %s\n", key, column, sheet.Cell(previous), row[i])
+			}
+			changed = true
+		}
+
+		return changed
+	}
+}
+
+// isPackDocument reports whether the documents of the collection are the documents of the pack, rather than folders
+// or embedded documents.
+func isPackDocument(pack packInfo, collection string) bool {
+	if pack.collection != "" {
+		return collection == pack.collection
+	}
+
+	return collection != "folders" && !strings.Contains(collection, ".")
+}
+
+// readCsv reads a CSV file written by export-csv. It returns its header and its rows by document id.
+func readCsv(file string) ([]string, map[string][]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open %s: %s\n", file, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read %s: %s\n", file, err)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%s is empty\n", file)
+	}
+
+	header := records[0]
+	idColumn := -1
+	for i, column := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		if header[i] == csvIdColumn {
+			idColumn = i
+		}
+	}
+	if idColumn < 0 {
+		return nil, nil, fmt.Errorf("%s has no %s column\n", file, csvIdColumn)
+	}
+
+	rows := map[string][]string{}
+	for _, record := range records[1:] {
+		if idColumn < len(record) && record[idColumn] != "" {
+			rows[record[idColumn]] = record
+		}
+	}
+
+	return header, rows, nil
+}
+
+func init() {
+	rootCmd.AddCommand(exportCsvCmd)
+	rootCmd.AddCommand(importCsvCmd)
+
+	addPacksFlags(exportCsvCmd)
+	exportCsvCmd.Flags().StringP("type", "t", "", "Only export the documents of this type, e.g. weapon")
+	exportCsvCmd.Flags().StringSliceP("columns", "c", nil, "Fields to export, as dotted paths, e.g. system.price.value")
+
+	addPacksFlags(importCsvCmd)
+	importCsvCmd.Flags().Bool("dry-run", false, "Only report the changes")
+}
@@ -0,0 +1,450 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/diff"
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
+	"github.com/djlechuck/fvtt-packs/internal/packer"
+	"github.com/djlechuck/fvtt-packs/internal/patch"
+	"github.com/djlechuck/fvtt-packs/internal/query"
+	"github.com/djlechuck/fvtt-packs/internal/serializer"
+	"github.com/spf13/cobra"
+)
+
+// documentSelector selects the documents to edit.
+type documentSelector struct {
+	docType string
+	filter  *query.Query
+	// embedded is whether the embedded documents are selected too, e.g. the items of the actors.
+	embedded bool
+}
+
+// editCmd represents the edit command
+var editCmd = &cobra.Command{
+	Use:   "edit [pack...]",
+	Short: "Apply a patch to the documents of the packs",
+	Long: `Apply a JSON Patch (RFC 6902) or a JSON Merge Patch (RFC 7396), written in JSON or YAML, to the documents of the
+given packs, or of every pack, e.g. to migrate the data of a system. The databases are edited, or the sources with the
+--sources flag. Folders are never edited.
+
+A JSON Patch, given with the --patch flag, is a list of operations whose paths are JSON Pointers, e.g.:
+[{"op": "move", "from": "/system/dmg", "path": "/system/damage"}]
+A document for which an operation fails, e.g. a test operation or the move of a missing field, is left untouched.
+
+A JSON Merge Patch, given with the --merge flag, is an object whose fields replace the ones of the documents, objects
+being merged and null removing a field, e.g.:
+{"flags": {"old-module": null}}
+
+The documents are selected by their type with the -t flag, and with a query expression giving true for them with the
+--filter flag (see the query command). The embedded documents, e.g. the items of the actors or the effects of the
+items, are edited with their parent as a whole, unless the --embedded flag is set: then they are selected and patched
+on their own too. Embedded documents added by a patch, e.g. with an add operation on /items/-, are given an _id and a
+_key. When editing the sources, the documents are patched as written in the files.
+
+Use the --dry-run flag to print the differences instead of writing them.
+
+For example:
+
+fvtt-packs edit items --patch rename-damage.json -t weapon --embedded --dry-run
+	Print the changes of the weapons of the items pack, and of the weapons of the actors, without writing them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := readPatch(cmd)
+		if err != nil {
+			return err
+		}
+
+		var selector documentSelector
+		selector.docType, _ = cmd.Flags().GetString("type")
+		selector.embedded, _ = cmd.Flags().GetBool("embedded")
+		if filter, _ := cmd.Flags().GetString("filter"); filter != "" {
+			if selector.filter, err = query.Parse(filter); err != nil {
+				return fmt.Errorf("invalid filter: %s\n", err)
+			}
+		}
+
+		fromSources, _ := cmd.Flags().GetBool("sources")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		packs, err := selectPacks(cmd, args, fromSources)
+		if err != nil {
+			return err
+		}
+
+		for _, pack := range packs {
+			var count int
+			if fromSources {
+				count, err = editPackSources(pack, p, selector, dryRun)
+			} else {
+				count, err = editPackDatabase(pack, p, selector, dryRun)
+			}
+			if err != nil {
+				return err
+			}
+			if !dryRun {
+				fmt.Println(pack.name, ":", count, "documents edited")
+			}
+		}
+
+		return nil
+	},
+}
+
+// readPatch reads the patch given by the patch or merge flag.
+func readPatch(cmd *cobra.Command) (patch.Patch, error) {
+	patchFile, _ := cmd.Flags().GetString("patch")
+	mergeFile, _ := cmd.Flags().GetString("merge")
+	if (patchFile == "") == (mergeFile == "") {
+		return nil, errors.New("give either a JSON Patch with --patch or a JSON Merge Patch with --merge\n")
+	}
+
+	file := patchFile + mergeFile
+	format, ok := serializer.ForFile(file)
+	if !ok {
+		return nil, fmt.Errorf("unsupported patch file %s\n", file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %s\n", file, err)
+	}
+	v, err := format.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %s\n", file, err)
+	}
+
+	if mergeFile != "" {
+		return patch.MergePatch{Value: v}, nil
+	}
+	ops, err := patch.ParseJSONPatch(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch %s: %s\n", file, err)
+	}
+
+	return ops, nil
+}
+
+// editPackDatabase applies the patch to the selected documents of the database of a pack. It returns the number of
+// changed documents.
+func editPackDatabase(pack packInfo, p patch.Patch, selector documentSelector, dryRun bool) (int, error) {
+	docs, err := packDocuments(pack, false)
+	if err != nil {
+		return 0, err
+	}
+
+	pretty, err := serializer.Get("json")
+	if err != nil {
+		return 0, err
+	}
+	pretty = serializer.Canonical(pretty)
+	updates := map[string][]byte{}
+	var deletes []string
+	count := 0
+	for _, doc := range docs {
+		before, edited, err := editDocument(pack.name, doc, p, selector)
+		if err != nil {
+			return 0, err
+		}
+		if edited == nil {
+			continue
+		}
+		count++
+
+		beforeText, _ := pretty.Encode(before)
+		afterText, _ := pretty.Encode(edited)
+		key, _ := doc["_key"].(string)
+		if dryRun {
+			fmt.Print(diff.Unified(pack.name+" "+key, pack.name+" "+key, string(beforeText), string(afterText)))
+			continue
+		}
+
+		previous, err := flattenEncoded(before)
+		if err != nil {
+			return 0, err
+		}
+		current, err := flattenEncoded(edited)
+		if err != nil {
+			return 0, err
+		}
+		for k, data := range current {
+			if string(previous[k]) != string(data) {
+				updates[k] = data
+			}
+		}
+		for k := range previous {
+			if _, ok := current[k]; !ok {
+				deletes = append(deletes, k)
+			}
+		}
+	}
+
+	if dryRun || len(updates)+len(deletes) == 0 {
+		return count, nil
+	}
+
+	db, err := fvttdb.OpenForWrite(pack.path)
+	if err != nil {
+		return 0, fmt.Errorf("cannot open db: %s\n", err)
+	}
+	defer db.Close()
+
+	for key, data := range updates {
+		if err := db.Put(key, data); err != nil {
+			return 0, err
+		}
+	}
+	for _, key := range deletes {
+		if err := db.Delete(key); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+// flattenEncoded returns the LevelDB entries of a document, encoded, by key.
+func flattenEncoded(doc map[string]interface{}) (map[string][]byte, error) {
+	entries, err := packer.Flatten(doc, "")
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := make(map[string][]byte, len(entries))
+	for _, e := range entries {
+		data, err := docpath.Encode(e.Value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode %s: %s\n", e.Key, err)
+		}
+		encoded[e.Key] = data
+	}
+
+	return encoded, nil
+}
+
+// editPackSources applies the patch to the selected documents of the sources of a pack. It returns the number of
+// changed documents.
+func editPackSources(pack packInfo, p patch.Patch, selector documentSelector, dryRun bool) (int, error) {
+	if _, err := os.Stat(pack.sources); err != nil {
+		fmt.Printf("warning: pack %s has no sources in %s\n", pack.name, pack.sources)
+		return 0, nil
+	}
+
+	count := 0
+	err := filepath.WalkDir(pack.sources, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || isFolderFile(file) {
+			return err
+		}
+
+		before, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		var after []byte
+		switch {
+		case serializer.IsLines(file):
+			var n int
+			if after, n, err = editLines(pack.name, file, p, selector); err != nil {
+				return err
+			}
+			count += n
+		case serializer.IsSource(file):
+			doc, err := serializer.ReadSource(file)
+			if err != nil {
+				return fmt.Errorf("cannot read %s: %s\n", file, err)
+			}
+			_, edited, err := editDocument(pack.name, doc, p, selector)
+			if err != nil || edited == nil {
+				return err
+			}
+			count++
+
+			format, _ := serializer.ForFile(file)
+			if cfg.Canonical {
+				format = serializer.Canonical(format)
+			}
+			encoded, err := format.Encode(edited)
+			if err != nil {
+				return fmt.Errorf("cannot encode %s: %s\n", file, err)
+			}
+			after = append(encoded, '\n')
+		}
+
+		if after == nil || string(after) == string(before) {
+			return nil
+		}
+		if dryRun {
+			fmt.Print(diff.Unified(file, file, string(before), string(after)))
+			return nil
+		}
+
+		return os.WriteFile(file, after, 0644)
+	})
+
+	return count, err
+}
+
+// editLines applies the patch to the selected documents of a JSON Lines file. It returns the new content of the file,
+// or nil if no document changed, and the number of changed documents.
+func editLines(pack string, file string, p patch.Patch, selector documentSelector) ([]byte, int, error) {
+	docs, err := serializer.ReadLines(file)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot read %s: %s\n", file, err)
+	}
+
+	lines := map[string][]byte{}
+	count := 0
+	for _, doc := range docs {
+		_, edited, err := editDocument(pack, doc, p, selector)
+		if err != nil {
+			return nil, 0, err
+		}
+		if edited != nil {
+			doc = edited
+			count++
+		}
+
+		id, _ := doc["_id"].(string)
+		if lines[id], err = serializer.Line.Encode(doc); err != nil {
+			return nil, 0, fmt.Errorf("cannot encode %s: %s\n", file, err)
+		}
+	}
+	if count == 0 {
+		return nil, 0, nil
+	}
+
+	return serializer.JoinLines(lines), count, nil
+}
+
+// editDocument applies the patch to a copy of the document if it is selected and, with the embedded option, to its
+// selected embedded documents. It returns a copy of the document as it was, and the edited copy, or nil if nothing
+// changed. A document which cannot be patched is left untouched, after printing why.
+func editDocument(pack string, doc map[string]interface{}, p patch.Patch, selector documentSelector) (map[string]interface{}, map[string]interface{}, error) {
+	if key, _ := doc["_key"].(string); strings.HasPrefix(key, "!folders!") {
+		return nil, nil, nil
+	}
+
+	data, err := docpath.Encode(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	before, _ := docpath.Decode(data)
+	copied, _ := docpath.Decode(data)
+	edited := copied.(map[string]interface{})
+
+	docpath.EachObject(edited, func(path string, obj map[string]interface{}) {
+		key, ok := obj["_key"].(string)
+		if !ok || err != nil || path != "" && !selector.embedded {
+			return
+		}
+
+		var selected bool
+		if selected, err = selector.matches(pack, obj); err != nil || !selected {
+			return
+		}
+
+		patched, patchErr := p.Apply(obj)
+		if m, ok := patched.(map[string]interface{}); patchErr == nil && !ok {
+			patchErr = errors.New("the patch does not give an object")
+		} else if patchErr == nil {
+			for k := range obj {
+				delete(obj, k)
+			}
+			for k, v := range m {
+				obj[k] = v
+			}
+			obj["_key"] = key
+		}
+		if patchErr != nil {
+			fmt.Printf("skipping %s %s: %s\n", pack, key, patchErr)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := setEmbeddedKeys(edited, randomId); err != nil {
+		fmt.Printf("skipping %s %s: %s\n", pack, doc["_key"], err)
+		return nil, nil, nil
+	}
+
+	after, err := docpath.Encode(edited)
+	if err != nil || string(after) == string(data) {
+		return nil, nil, err
+	}
+
+	return before.(map[string]interface{}), edited, nil
+}
+
+// randomId returns a new document id, made of 16 alphanumeric characters like the ones of Foundry, for an embedded
+// document added by a patch.
+func randomId(parentId string, field string, index int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	id := make([]byte, 16)
+	for i := range id {
+		id[i] = chars[rand.IntN(len(chars))]
+	}
+
+	return string(id)
+}
+
+// matches reports whether the document, of the given pack, is selected.
+func (s documentSelector) matches(pack string, doc map[string]interface{}) (bool, error) {
+	if s.docType != "" && doc["type"] != s.docType {
+		return false, nil
+	}
+	if s.filter == nil {
+		return true, nil
+	}
+
+	values, err := s.filter.Run(doc, map[string]interface{}{"pack": pack})
+	if err != nil {
+		return false, fmt.Errorf("cannot evaluate the filter against %s: %s\n", doc["_key"], err)
+	}
+	for _, v := range values {
+		if v != nil && v != false {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+
+	addPacksFlags(editCmd)
+	editCmd.Flags().String("patch", "", "JSON Patch file, in JSON or YAML")
+	editCmd.Flags().String("merge", "", "JSON Merge Patch file, in JSON or YAML")
+	editCmd.Flags().StringP("type", "t", "", "Only edit the documents of this type, e.g. weapon")
+	editCmd.Flags().String("filter", "", "Only edit the documents for which this query expression gives true")
+	editCmd.Flags().Bool("embedded", false, "Select and patch the embedded documents too")
+	editCmd.Flags().Bool("sources", false, "Edit the sources instead of the databases")
+	editCmd.Flags().Bool("dry-run", false, "Only print the differences")
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRandomId(t *testing.T) {
+	pattern := regexp.MustCompile(`^[a-zA-Z0-9]{16}$`)
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		id := randomId("aaaaaaaaaaaaaaaa", "items", 0)
+		if !pattern.MatchString(id) {
+			t.Fatalf("randomId() = %s, expected 16 alphanumeric characters", id)
+		}
+		if seen[id] {
+			t.Fatalf("randomId() gives %s twice", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,305 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/djlechuck/fvtt-packs/internal/generator"
+	"github.com/djlechuck/fvtt-packs/internal/serializer"
+	"github.com/spf13/cobra"
+)
+
+// generateCmd represents the generate command
+var generateCmd = &cobra.Command{
+	Use:   "generate <pack> <template> <data>",
+	Short: "Generate source documents from a data file and a template",
+	Long: `Generate the source documents of a pack from a data file and a template, e.g. to import an equipment list from
+a spreadsheet. The data file is a CSV file, whose first line holds the column names, or a YAML or JSON file holding a
+list of objects. Each row gives a document.
+
+The template is a Go text/template (https://pkg.go.dev/text/template) rendering a document as YAML, or JSON, with the
+row as dot. The ids of the documents are derived from the pack and the key column of the rows, given by the --key
+flag, so that generating the documents again updates them instead of creating new ones. The template can use these
+functions:
+* id: the id of the document
+* childId "name": another id derived from the id of the document, e.g. for its embedded documents, which are given
+  one derived from their position when the template gives them none
+* json .column: the value as JSON, e.g. to quote a string
+* split .column ";": the value split into a list
+* trim, lower and upper
+
+The documents are written according to the layout of the pack: with the jsonl layout, into its JSON Lines file, and
+with the folders layout, into the directory of the folder given by their folder field.
+
+For example, with a weapons.csv file holding name, price and damage columns, and a weapon.yml template:
+
+name: {{json .name}}
+type: weapon
+img: icons/weapons/swords/sword-guard.webp
+system:
+  price:
+    value: {{.price}}
+  damage:
+    parts: [[{{json .damage}}, slashing]]
+
+fvtt-packs generate weapons weapon.yml weapons.csv
+	Write a source file per weapon, in the format of the pack, which pack then packs as usual.`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		packs, _, err := declaredPacks(cmd)
+		if err != nil {
+			return err
+		}
+		var pack *packInfo
+		for i := range packs {
+			if packs[i].name == args[0] {
+				pack = &packs[i]
+			}
+		}
+		if pack == nil {
+			return fmt.Errorf("no pack \"%s\" found\n", args[0])
+		}
+
+		g, err := generator.New(args[1], pack.name)
+		if err != nil {
+			return fmt.Errorf("cannot read template: %s\n", err)
+		}
+		rows, err := generator.ReadData(args[2])
+		if err != nil {
+			return fmt.Errorf("cannot read data: %s\n", err)
+		}
+
+		packCfg := cfg.Pack(pack.name)
+		format, err := serializer.Get(packCfg.Format)
+		if err != nil {
+			return err
+		}
+		namer := serializer.NewNamer(packCfg.Filename)
+		existing, err := sourceFiles(pack.sources, namer)
+		if err != nil {
+			return err
+		}
+
+		// With the jsonl layout, the new documents are written into the JSON Lines file of the pack, and with the
+		// folders layout, into the directory of their folder.
+		linesFile := filepath.Join(pack.sources, pack.name+serializer.LinesExtension)
+		var lines map[string][]byte
+		if packCfg.Layout == "jsonl" {
+			if lines, err = readLinesById(linesFile); err != nil {
+				return err
+			}
+		}
+		var folderDirs map[string]string
+		if packCfg.Layout == "folders" {
+			if folderDirs, err = folderDirectories(pack.sources); err != nil {
+				return err
+			}
+		}
+
+		keyColumn, _ := cmd.Flags().GetString("key")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		generated := map[string]int{}
+		for i, row := range rows {
+			key := fmt.Sprint(row[keyColumn])
+			if row[keyColumn] == nil || key == "" {
+				return fmt.Errorf("row %d has no %s\n", i+1, keyColumn)
+			}
+
+			doc, err := g.Render(row, key)
+			if err != nil {
+				return fmt.Errorf("cannot generate row %d: %s\n", i+1, err)
+			}
+
+			id := fmt.Sprint(doc["_id"])
+			if previous, ok := generated[id]; ok {
+				return fmt.Errorf("rows %d and %d give the same document %s\n", previous, i+1, id)
+			}
+			generated[id] = i + 1
+
+			if _, ok := doc["_key"]; !ok {
+				if pack.collection == "" {
+					return fmt.Errorf("the type of the documents of pack %s is unknown, give their _key in the template\n", pack.name)
+				}
+				doc["_key"] = "!" + pack.collection + "!" + id
+			}
+			if err := setEmbeddedKeys(doc, embeddedId); err != nil {
+				return fmt.Errorf("cannot generate row %d: %s\n", i+1, err)
+			}
+
+			file, exists := existing[id]
+			if !exists && lines != nil {
+				action := "creating"
+				if _, ok := lines[id]; ok {
+					action = "updating"
+				}
+				fmt.Println(action, id, "in", linesFile)
+				if lines[id], err = serializer.Line.Encode(doc); err != nil {
+					return fmt.Errorf("cannot encode %s: %s\n", id, err)
+				}
+				continue
+			}
+
+			var dir string
+			if folderDirs != nil {
+				folder, _ := doc["folder"].(string)
+				dir = folderDirs[folder]
+				if folder != "" && dir == "" {
+					fmt.Printf("warning: %s is inside the unknown folder %s\n", id, folder)
+				}
+			}
+
+			action := "updating"
+			previous := ""
+			if exists && folderDirs != nil && filepath.Dir(file) != filepath.Join(pack.sources, filepath.FromSlash(dir)) {
+				// The document moves to the directory of its new folder.
+				previous, exists = file, false
+			}
+			if !exists {
+				name, _ := doc["name"].(string)
+				docType, _ := doc["type"].(string)
+				file = filepath.Join(pack.sources, filepath.FromSlash(namer.NameOf(dir, name, id, docType, format)))
+				action = "creating"
+			}
+
+			if previous != "" {
+				fmt.Println("moving", previous, "to", file)
+			} else {
+				fmt.Println(action, file)
+			}
+			if dryRun {
+				continue
+			}
+			if err := serializer.Write(doc, file, format); err != nil {
+				return fmt.Errorf("cannot write %s: %s\n", file, err)
+			}
+			if previous != "" {
+				if err := os.Remove(previous); err != nil {
+					return fmt.Errorf("cannot remove %s: %s\n", previous, err)
+				}
+			}
+		}
+
+		if lines != nil && !dryRun {
+			if err := serializer.WriteLines(linesFile, lines); err != nil {
+				return fmt.Errorf("cannot write %s: %s\n", linesFile, err)
+			}
+		}
+
+		fmt.Println(len(rows), "documents generated")
+
+		return nil
+	},
+}
+
+// embeddedId returns the id of an embedded document given by the template without one, derived from the id of its
+// parent like the ids given by childId, so that generating the documents again gives the same ids.
+func embeddedId(parentId string, field string, index int) string {
+	return generator.StableId(parentId, field, strconv.Itoa(index))
+}
+
+// sourceFiles returns the source files of the sources directory of a pack by document id, and reserves their names
+// in the namer.
+func sourceFiles(dir string, namer *serializer.Namer) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) && p == dir {
+			return filepath.SkipDir
+		}
+		if err != nil || d.IsDir() || !serializer.IsSource(p) {
+			return err
+		}
+
+		if rel, err := filepath.Rel(dir, p); err == nil {
+			namer.Reserve(filepath.ToSlash(rel))
+		}
+		doc, err := serializer.ReadSource(p)
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %s\n", p, err)
+		}
+		if id, ok := doc["_id"].(string); ok {
+			files[id] = p
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+// readLinesById returns the documents of the JSON Lines file of a pack, encoded with serializer.Line, by id. It returns
+// an empty map if the file does not exist.
+func readLinesById(file string) (map[string][]byte, error) {
+	lines := map[string][]byte{}
+	docs, err := serializer.ReadLines(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return lines, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %s\n", file, err)
+	}
+
+	for _, doc := range docs {
+		id, _ := doc["_id"].(string)
+		if lines[id], err = serializer.Line.Encode(doc); err != nil {
+			return nil, fmt.Errorf("cannot encode %s: %s\n", id, err)
+		}
+	}
+
+	return lines, nil
+}
+
+// folderDirectories returns the directories of the folders of the sources directory of a pack, relative to it and
+// slash-separated as given to the namer, by folder id.
+func folderDirectories(dir string) (map[string]string, error) {
+	dirs := map[string]string{}
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return dirs, nil
+	}
+
+	ids, err := readFolderIds(dir)
+	if err != nil {
+		return nil, err
+	}
+	for folderDir, id := range ids {
+		if rel, err := filepath.Rel(dir, folderDir); err == nil {
+			dirs[id] = filepath.ToSlash(rel)
+		}
+	}
+
+	return dirs, nil
+}
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+
+	addPacksFlags(generateCmd)
+	generateCmd.Flags().String("key", "name", "Column identifying the rows, from which the ids of the documents are derived")
+	generateCmd.Flags().String("layout", "flat", "Layout of the files: flat, folders to mirror the folders of the pack, or jsonl for a single file")
+	generateCmd.Flags().Bool("dry-run", false, "Only report the files which would be written")
+}
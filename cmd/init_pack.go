@@ -0,0 +1,146 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/documents"
+	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
+	"github.com/djlechuck/fvtt-packs/internal/manifest"
+	"github.com/spf13/cobra"
+)
+
+// initPackCmd represents the init-pack command
+var initPackCmd = &cobra.Command{
+	Use:   "init-pack <name>",
+	Short: "Create a new empty pack and declare it in the manifest",
+	Long: `Create a new empty pack: its LevelDB inside the packs directory and its sources directory. The pack is then
+declared in the packs of the module.json or system.json manifest.
+
+For example:
+
+fvtt-packs init-pack weapons -t Item -l "Weapons" -f "Equipment/Martial"
+	Create an Item pack labelled "Weapons", placed inside the Martial pack folder of the Equipment pack folder.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if strings.ContainsAny(name, ". /\\") {
+			return fmt.Errorf("invalid pack name \"%s\": it cannot contain dots, spaces or slashes\n", name)
+		}
+
+		docType, _ := cmd.Flags().GetString("type")
+		if !documents.IsPackType(docType) {
+			return fmt.Errorf("invalid pack type \"%s\"\n", docType)
+		}
+
+		m, err := loadManifest(cmd)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			return errors.New("no module.json or system.json manifest found")
+		}
+		if m.Pack(name) != nil {
+			return fmt.Errorf("pack %s is already declared in %s\n", name, filepath.Base(m.File))
+		}
+
+		d := cfg.Directory
+		pack := manifest.Pack{
+			Name:      name,
+			Path:      filepath.ToSlash(filepath.Join(d, name)),
+			Type:      docType,
+			Ownership: map[string]string{"PLAYER": "OBSERVER", "ASSISTANT": "OWNER"},
+		}
+
+		pack.Label, _ = cmd.Flags().GetString("label")
+		if pack.Label == "" {
+			pack.Label = name
+		}
+
+		pack.System, _ = cmd.Flags().GetString("system")
+		if pack.System == "" {
+			pack.System = defaultPackSystem(m)
+		}
+
+		folder, _ := cmd.Flags().GetString("folder")
+		var folders []string
+		if folder != "" {
+			folders = strings.Split(folder, "/")
+			m.AddToFolder(folders, name)
+		}
+
+		root := filepath.Dir(m.File)
+		db, err := fvttdb.Create(filepath.Join(root, pack.DatabasePath()))
+		if err != nil {
+			return err
+		}
+		db.Close()
+
+		var dirs []string
+		for _, f := range folders {
+			dirs = append(dirs, folderDirectory(f))
+		}
+		if err := os.MkdirAll(sourcesDirectory(root, name, dirs), 0755); err != nil {
+			return fmt.Errorf("cannot create sources directory: %s\n", err)
+		}
+
+		m.Packs = append(m.Packs, pack)
+		if err := m.Save(); err != nil {
+			return err
+		}
+
+		fmt.Println("pack", name, "created and declared in", filepath.Base(m.File))
+
+		return nil
+	},
+}
+
+// defaultPackSystem returns the system of a new pack: the system itself, or the system of the other packs of a module.
+func defaultPackSystem(m *manifest.Manifest) string {
+	if m.IsSystem() {
+		return m.Id
+	}
+
+	for _, p := range m.Packs {
+		if p.System != "" {
+			return p.System
+		}
+	}
+
+	return ""
+}
+
+func init() {
+	rootCmd.AddCommand(initPackCmd)
+
+	addPacksFlags(initPackCmd)
+	initPackCmd.Flags().StringP("type", "t", "Item", "Type of the documents of the pack")
+	initPackCmd.Flags().StringP("label", "l", "", "Label of the pack (default is its name)")
+	initPackCmd.Flags().StringP("system", "s", "", "System of the pack (default is the system of the other packs)")
+	initPackCmd.Flags().StringP("folder", "f", "", "Pack folder where the pack is placed, nested folders being separated by slashes")
+}
@@ -0,0 +1,257 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/djlechuck/fvtt-packs/internal/query"
+	"github.com/djlechuck/fvtt-packs/internal/serializer"
+	"github.com/spf13/cobra"
+)
+
+// lsSortColumns are the columns the documents can be sorted by.
+var lsSortColumns = []string{"name", "id", "type", "folder"}
+
+// lsCmd represents the ls command
+var lsCmd = &cobra.Command{
+	Use:   "ls <pack>",
+	Short: "List the documents of a pack",
+	Long: `List the documents of a pack, read from its database or from its sources with the --sources flag, with their id,
+name, type, folder and number of embedded documents, e.g. the items of the actors.
+
+The documents are selected by their type with the -t flag, and with a query expression giving true for them with the
+--filter flag (see the query command). They are sorted by name, or by id, type or folder with the --sort flag.
+
+For example:
+
+fvtt-packs ls monsters -t npc --sort folder
+	List the NPCs of the monsters pack, by folder.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fromSources, _ := cmd.Flags().GetBool("sources")
+		docs, err := selectedPackDocuments(cmd, args[0], fromSources)
+		if err != nil {
+			return err
+		}
+
+		var selector documentSelector
+		selector.docType, _ = cmd.Flags().GetString("type")
+		if filter, _ := cmd.Flags().GetString("filter"); filter != "" {
+			if selector.filter, err = query.Parse(filter); err != nil {
+				return fmt.Errorf("invalid filter: %s\n", err)
+			}
+		}
+		sortBy, _ := cmd.Flags().GetString("sort")
+		column := -1
+		for i, c := range lsSortColumns {
+			if c == sortBy {
+				column = i
+			}
+		}
+		if column < 0 {
+			return fmt.Errorf("unknown sort column %s, use %s\n", sortBy, strings.Join(lsSortColumns, ", "))
+		}
+
+		folders := map[string]map[string]interface{}{}
+		for _, doc := range docs {
+			if key, _ := doc["_key"].(string); strings.HasPrefix(key, "!folders!") {
+				id, _ := doc["_id"].(string)
+				folders[id] = doc
+			}
+		}
+
+		var rows [][]string
+		for _, doc := range docs {
+			if key, _ := doc["_key"].(string); strings.HasPrefix(key, "!folders!") {
+				continue
+			}
+			selected, err := selector.matches(args[0], doc)
+			if err != nil {
+				return err
+			}
+			if !selected {
+				continue
+			}
+
+			id, _ := doc["_id"].(string)
+			name, _ := doc["name"].(string)
+			docType, _ := doc["type"].(string)
+			rows = append(rows, []string{name, id, docType, folderPath(folders, doc), embeddedCounts(doc)})
+		}
+
+		sort.SliceStable(rows, func(i, j int) bool {
+			for _, c := range []int{column, 0, 1} {
+				if rows[i][c] != rows[j][c] {
+					return rows[i][c] < rows[j][c]
+				}
+			}
+			return false
+		})
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "id\tname\ttype\tfolder\tembedded")
+		for _, row := range rows {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", row[1], shortenCell(row[0]), row[2], row[3], row[4])
+		}
+		w.Flush()
+
+		fmt.Println(len(rows), "documents")
+
+		return nil
+	},
+}
+
+// getCmd represents the get command
+var getCmd = &cobra.Command{
+	Use:   "get <pack> <id|name>",
+	Short: "Print a document of a pack",
+	Long: `Print a document of a pack, given by its id or its name, with its embedded documents, e.g. the items of an
+actor. It is read from the database of the pack, or from its sources with the --sources flag, and printed as JSON, or
+as YAML with the --format flag.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		encoder, err := serializer.Get(format)
+		if err != nil {
+			return err
+		}
+
+		fromSources, _ := cmd.Flags().GetBool("sources")
+		docs, err := selectedPackDocuments(cmd, args[0], fromSources)
+		if err != nil {
+			return err
+		}
+
+		var found []map[string]interface{}
+		for _, doc := range docs {
+			if doc["_id"] == args[1] {
+				found = []map[string]interface{}{doc}
+				break
+			}
+			if name, _ := doc["name"].(string); strings.EqualFold(name, args[1]) {
+				found = append(found, doc)
+			}
+		}
+
+		switch len(found) {
+		case 0:
+			return fmt.Errorf("no document \"%s\" found in pack %s\n", args[1], args[0])
+		case 1:
+		default:
+			var keys []string
+			for _, doc := range found {
+				keys = append(keys, fmt.Sprint(doc["_key"]))
+			}
+			return fmt.Errorf("several documents are named \"%s\", give the id of one of them: %s\n", args[1], strings.Join(keys, ", "))
+		}
+
+		data, err := serializer.Canonical(encoder).Encode(found[0])
+		if err != nil {
+			return fmt.Errorf("cannot encode the document: %s\n", err)
+		}
+		fmt.Println(strings.TrimRight(string(data), "\n"))
+
+		return nil
+	},
+}
+
+// selectedPackDocuments returns the documents of the pack having the given name, as packDocuments does.
+func selectedPackDocuments(cmd *cobra.Command, name string, fromSources bool) ([]map[string]interface{}, error) {
+	packs, err := selectPacks(cmd, []string{name}, fromSources)
+	if err != nil {
+		return nil, err
+	}
+
+	return packDocuments(packs[0], fromSources)
+}
+
+// folderPath returns the names of the folders containing the document, outermost first, separated by slashes.
+func folderPath(folders map[string]map[string]interface{}, doc map[string]interface{}) string {
+	var names []string
+	seen := map[string]bool{}
+	for id, _ := doc["folder"].(string); id != "" && !seen[id]; id, _ = doc["folder"].(string) {
+		seen[id] = true
+		folder, ok := folders[id]
+		if !ok {
+			names = append(names, id)
+			break
+		}
+		name, _ := folder["name"].(string)
+		names = append(names, name)
+		doc = folder
+	}
+
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+
+	return strings.Join(names, "/")
+}
+
+// embeddedCounts returns the number of embedded documents of the document by collection, e.g. "effects=1 items=3".
+func embeddedCounts(doc map[string]interface{}) string {
+	var counts []string
+	for _, field := range sortedFields(doc) {
+		list, ok := doc[field].([]interface{})
+		if !ok || len(list) == 0 {
+			continue
+		}
+		if first, ok := list[0].(map[string]interface{}); ok {
+			if _, ok := first["_key"]; ok {
+				counts = append(counts, fmt.Sprintf("%s=%d", field, len(list)))
+			}
+		}
+	}
+
+	return strings.Join(counts, " ")
+}
+
+func sortedFields(doc map[string]interface{}) []string {
+	fields := make([]string, 0, len(doc))
+	for f := range doc {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+func init() {
+	rootCmd.AddCommand(lsCmd)
+	rootCmd.AddCommand(getCmd)
+
+	addPacksFlags(lsCmd)
+	lsCmd.Flags().StringP("type", "t", "", "Only list the documents of this type, e.g. weapon")
+	lsCmd.Flags().String("filter", "", "Only list the documents for which this query expression gives true")
+	lsCmd.Flags().String("sort", "name", "Sort the documents by "+strings.Join(lsSortColumns, ", "))
+	lsCmd.Flags().Bool("sources", false, "Read the sources instead of the database")
+
+	addPacksFlags(getCmd)
+	getCmd.Flags().String("format", "json", "Format of the document: json or yaml")
+	getCmd.Flags().Bool("sources", false, "Read the sources instead of the database")
+}
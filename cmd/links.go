@@ -0,0 +1,185 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+	"github.com/djlechuck/fvtt-packs/internal/links"
+	"github.com/spf13/cobra"
+)
+
+// linkOccurrence is a compendium link found in a field of a pack entry.
+type linkOccurrence struct {
+	pack  string
+	key   string
+	field string
+	link  links.Link
+}
+
+// checkLinksCmd represents the check-links command
+var checkLinksCmd = &cobra.Command{
+	Use:   "check-links",
+	Short: "Report compendium links pointing to missing documents",
+	Long: `Parse the @UUID[Compendium...] and @Compendium[...] links of every text field of the packs, as well as fields
+holding a compendium UUID like _stats.compendiumSource, then report the links whose target does not exist.
+
+Links pointing to another module are not checked. The id of your module or system is read from its manifest, so that
+links to packs which do not exist anymore are reported too. Without manifest, give it with the -m flag:
+fvtt-packs check-links -m my-module`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		packs, m, err := discoverPacks(cmd)
+		if err != nil {
+			return err
+		}
+
+		module, _ := cmd.Flags().GetString("module")
+		if module == "" && m != nil {
+			module = m.Id
+		}
+		index := links.NewIndex(module)
+		var occurrences []linkOccurrence
+
+		for _, pack := range packs {
+			pName := pack.name
+			index.AddPack(pName)
+
+			err := eachPackEntry(pack.path, func(key string, collection string, id string, v interface{}) error {
+				if !strings.Contains(collection, ".") {
+					m, _ := v.(map[string]interface{})
+					name, _ := m["name"].(string)
+					index.Add(pName, collection, id, name)
+				}
+
+				docpath.MapStrings(v, func(path string, s string) string {
+					found := links.Find(s)
+					if l, ok := links.ParseUUID(s); ok {
+						found = append(found, l)
+					}
+					for _, l := range found {
+						occurrences = append(occurrences, linkOccurrence{pack: pName, key: key, field: path, link: l})
+					}
+
+					return s
+				})
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		dangling := 0
+		for _, o := range occurrences {
+			if !index.Checkable(o.link) {
+				continue
+			}
+			if err := index.Validate(o.link); err != nil {
+				fmt.Printf("%s %s %s: %s: %s\n", o.pack, o.key, o.field, o.link.Raw, err)
+				dangling++
+			}
+		}
+
+		fmt.Println(len(occurrences), "links checked,", dangling, "dangling")
+		if dangling > 0 {
+			return fmt.Errorf("%d dangling links found\n", dangling)
+		}
+
+		return nil
+	},
+}
+
+// rewriteLinksCmd represents the rewrite-links command
+var rewriteLinksCmd = &cobra.Command{
+	Use:   "rewrite-links",
+	Short: "Rewrite compendium links after renaming a pack or moving a document",
+	Long: `Rewrite the compendium links of every pack which point inside the --from target so that they point inside the
+--to target. Targets are written module, module.pack or module.pack.id.
+
+For example:
+
+fvtt-packs rewrite-links --from my-module.weapons --to my-module.equipment
+	Rewrite the links to the weapons pack after it has been renamed equipment.
+
+fvtt-packs rewrite-links --from my-module.weapons.Ab12Cd34Ef56Gh78 --to my-module.equipment
+	Rewrite the links to a single document which has been moved from the weapons pack to the equipment pack.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		if from == "" || to == "" {
+			return errors.New("both --from and --to must be given")
+		}
+		rules := []links.Rule{{From: links.ParseTarget(from), To: links.ParseTarget(to)}}
+
+		packs, _, err := discoverPacks(cmd)
+		if err != nil {
+			return err
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		for _, pack := range packs {
+			pName := pack.name
+			count, err := rewritePackEntries(pack.path, dryRun, func(key string, v interface{}) bool {
+				changed := false
+				docpath.MapStrings(v, func(path string, s string) string {
+					ns, n := links.Rewrite(s, rules)
+					if n > 0 {
+						fmt.Printf("%s %s %s: %d links rewritten\n", pName, key, path, n)
+						changed = true
+					}
+
+					return ns
+				})
+
+				return changed
+			})
+			if err != nil {
+				return err
+			}
+
+			if count > 0 {
+				fmt.Println(pName, ":", count, "entries updated")
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkLinksCmd)
+	rootCmd.AddCommand(rewriteLinksCmd)
+
+	addPacksFlags(checkLinksCmd)
+	checkLinksCmd.Flags().StringP("module", "m", "", "Id of the module or system owning the packs (default is the id of the manifest)")
+
+	addPacksFlags(rewriteLinksCmd)
+	rewriteLinksCmd.Flags().String("from", "", "Target of the links to rewrite: module, module.pack or module.pack.id")
+	rewriteLinksCmd.Flags().String("to", "", "New target of the links: module, module.pack or module.pack.id")
+	rewriteLinksCmd.Flags().Bool("dry-run", false, "Only report the links which would be rewritten")
+}
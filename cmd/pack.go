@@ -0,0 +1,342 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/exclude"
+	"github.com/djlechuck/fvtt-packs/internal/packer"
+	"github.com/djlechuck/fvtt-packs/internal/richtext"
+	"github.com/djlechuck/fvtt-packs/internal/serializer"
+	"github.com/djlechuck/fvtt-packs/internal/sparse"
+	"github.com/spf13/cobra"
+)
+
+// packCmd represents the pack command
+var packCmd = &cobra.Command{
+	Use:   "pack",
+	Short: "Pack human-readable files into LevelDB",
+	Long: `Pack the human-readable files of the _pack_sources directory into LevelDB. JSON, YAML and TOML files are
+supported, and can be mixed. The sources are validated first: nothing is written if one of them is invalid.
+
+The rich-text fields extracted by unpack --html or --markdown are inlined back, Markdown being converted to HTML.
+
+The fields left out by unpack, according to the exclude setting of the project config file, are taken back from the
+current database of the pack. The statistics missing from it are set to null. With the --sparse flag, or the sparse
+setting of the project config file, the fields left out by unpack --sparse, and more generally the fields of tokens and
+scenes missing from the sources, get the default value of Foundry v12.
+
+With the --layout folders flag, or the layout setting of the project config file, the folder of each document is the
+one of its directory, so that moving a file to another directory moves the document to another folder.
+
+The .jsonl files written with the jsonl layout are read whatever the layout, each of their lines being a document.
+
+The human-readable files are read from the same place unpack writes them: the -o flag or the output setting of the
+project config file, and the sources setting of each pack.
+
+The packs are read from the module.json or system.json manifest of the current directory. Without manifest, every
+directory of _pack_sources is packed inside the packs directory, which you can override with the -d flag:
+fvtt-packs pack -d mypacks`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		packs, err := sourcePacks(cmd)
+		if err != nil {
+			return err
+		}
+
+		built, err := readAndValidatePacks(packs)
+		if err != nil {
+			return err
+		}
+
+		for i, pack := range packs {
+			if built[i] == nil {
+				continue
+			}
+
+			fmt.Println("packing", pack.name, "...")
+			if err := writePack(pack, built[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+// writePack writes the entries read from the sources of a pack into its database, once their excluded and default
+// fields are restored.
+func writePack(pack packInfo, entries []packer.Entry) error {
+	if err := restoreExcludedFields(pack, entries); err != nil {
+		return fmt.Errorf("cannot restore the excluded fields of pack %s: %s\n", pack.name, err)
+	}
+	if cfg.Sparse {
+		for _, e := range entries {
+			sparse.Expand(e.Value, sparse.Collection(e.Key))
+		}
+	}
+	if err := packer.Write(pack.path, entries); err != nil {
+		return fmt.Errorf("cannot write pack %s: %s\n", pack.name, err)
+	}
+
+	return nil
+}
+
+// restoreExcludedFields gives back to the entries the fields left out of their sources when unpacking, taking them from
+// the current database of the pack when there is one.
+func restoreExcludedFields(pack packInfo, entries []packer.Entry) error {
+	patterns := cfg.Pack(pack.name).Exclude
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	previous := map[string]map[string]interface{}{}
+	if s, err := os.Stat(pack.path); err == nil && s.IsDir() {
+		err := eachPackEntry(pack.path, func(key string, collection string, id string, v interface{}) error {
+			if m, ok := v.(map[string]interface{}); ok {
+				previous[key] = m
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, e := range entries {
+		exclude.Restore(e.Value, previous[e.Key], patterns)
+	}
+
+	return nil
+}
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the human-readable files before packing them",
+	Long: `Check the human-readable files of the _pack_sources directory: every file must be readable and contain a
+document with a valid _key and _id, the keys must be unique and the documents must be of the type of their pack.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		packs, err := sourcePacks(cmd)
+		if err != nil {
+			return err
+		}
+
+		if _, err := readAndValidatePacks(packs); err != nil {
+			return err
+		}
+
+		fmt.Println("all sources are valid")
+
+		return nil
+	},
+}
+
+// sourcePacks returns the packs to build from sources. Without manifest, they are the directories of the sources.
+func sourcePacks(cmd *cobra.Command) ([]packInfo, error) {
+	m, err := loadManifest(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		packs, _, err := declaredPacks(cmd)
+		return packs, err
+	}
+
+	p, err := projectRoot(cmd)
+	if err != nil {
+		return nil, err
+	}
+	d := cfg.Directory
+
+	entries, err := os.ReadDir(sourcesRoot(p))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read sources directory: %s\n", err)
+	}
+
+	var packs []packInfo
+	for _, e := range entries {
+		if e.IsDir() {
+			packs = append(packs, packInfo{name: e.Name(), path: filepath.Join(p, d, e.Name()), sources: sourcesDirectory(p, e.Name(), nil)})
+		}
+	}
+
+	return packs, nil
+}
+
+// readAndValidatePacks reads the sources of the packs and validates them. The entries of a pack without sources are
+// nil. An error is returned if any source is invalid, after printing every problem.
+func readAndValidatePacks(packs []packInfo) ([][]packer.Entry, error) {
+	built := make([][]packer.Entry, len(packs))
+	invalid := 0
+
+	for i, pack := range packs {
+		entries, err := readPackSources(pack)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("warning: pack %s has no sources in %s\n", pack.name, pack.sources)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		errs := packer.Validate(entries, pack.collection)
+		for _, err := range errs {
+			fmt.Println(pack.name, ":", err)
+		}
+		invalid += len(errs)
+		built[i] = entries
+	}
+
+	if invalid > 0 {
+		return nil, fmt.Errorf("%d problems found in the sources\n", invalid)
+	}
+
+	return built, nil
+}
+
+// readPackSources reads every source file of the pack and flattens them into LevelDB entries. When the files mirror the
+// folders of the pack, the folder of each document is the one of its directory.
+func readPackSources(pack packInfo) ([]packer.Entry, error) {
+	dir := pack.sources
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+
+	var folders map[string]string
+	if cfg.Pack(pack.name).Layout == "folders" {
+		var err error
+		if folders, err = readFolderIds(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []packer.Entry
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && serializer.IsLines(p) {
+			return readLines(p, &entries)
+		}
+		if err != nil || d.IsDir() || !serializer.IsSource(p) {
+			return err
+		}
+
+		doc, err := serializer.ReadSource(p)
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %s\n", p, err)
+		}
+		if err := richtext.Inline(doc, filepath.Dir(p)); err != nil {
+			return fmt.Errorf("cannot read %s: %s\n", p, err)
+		}
+
+		if folders != nil {
+			docDir := filepath.Dir(p)
+			if isFolderFile(p) {
+				docDir = filepath.Dir(docDir)
+			}
+			doc["folder"] = directoryFolder(folders, dir, docDir)
+		}
+
+		docEntries, err := packer.Flatten(doc, p)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, docEntries...)
+
+		return nil
+	})
+
+	return entries, err
+}
+
+// readLines reads the documents of a JSON Lines file and flattens them into LevelDB entries.
+func readLines(p string, entries *[]packer.Entry) error {
+	docs, err := serializer.ReadLines(p)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %s\n", p, err)
+	}
+
+	for i, doc := range docs {
+		docEntries, err := packer.Flatten(doc, fmt.Sprintf("%s:%d", p, i+1))
+		if err != nil {
+			return err
+		}
+		*entries = append(*entries, docEntries...)
+	}
+
+	return nil
+}
+
+// readFolderIds returns the ids of the folders of the sources directory of a pack, by directory.
+func readFolderIds(dir string) (map[string]string, error) {
+	folders := map[string]string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !isFolderFile(p) {
+			return err
+		}
+
+		doc, err := serializer.ReadSource(p)
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %s\n", p, err)
+		}
+		if id, ok := doc["_id"].(string); ok {
+			folders[filepath.Dir(p)] = id
+		}
+
+		return nil
+	})
+
+	return folders, err
+}
+
+// isFolderFile reports whether the file holds the metadata of the folder of its directory.
+func isFolderFile(p string) bool {
+	return serializer.IsSource(p) && strings.TrimSuffix(filepath.Base(p), filepath.Ext(p)) == serializer.FolderFileName
+}
+
+// directoryFolder returns the id of the folder of the nearest directory having one, up to the sources directory of the
+// pack, or nil if there is none.
+func directoryFolder(folders map[string]string, root string, dir string) interface{} {
+	for ; dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if id, ok := folders[dir]; ok {
+			return id
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(packCmd)
+	rootCmd.AddCommand(validateCmd)
+
+	addPacksFlags(packCmd)
+	packCmd.Flags().String("layout", "flat", "Layout of the files: flat, or folders when they mirror the folders of the pack")
+	packCmd.Flags().Bool("sparse", false, "Give the default value of Foundry to the fields missing from the files")
+	addPacksFlags(validateCmd)
+	validateCmd.Flags().String("layout", "flat", "Layout of the files: flat, or folders when they mirror the folders of the pack")
+}
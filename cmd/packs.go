@@ -0,0 +1,417 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+	"github.com/djlechuck/fvtt-packs/internal/documents"
+	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
+	"github.com/djlechuck/fvtt-packs/internal/manifest"
+	"github.com/djlechuck/fvtt-packs/internal/packer"
+	"github.com/spf13/cobra"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+// packInfo locates a pack of the project.
+type packInfo struct {
+	name string
+	// path is the LevelDB directory of the pack.
+	path string
+	// collection is the collection of the documents of the pack, empty when the pack is not declared in a manifest.
+	collection string
+	// folders are the names of the pack folders containing the pack, outermost first.
+	folders []string
+	// sources is the directory of the human-readable files of the pack.
+	sources string
+}
+
+// sourcesRoot returns the directory containing the human-readable files of all the packs.
+func sourcesRoot(root string) string {
+	return resolvePath(root, cfg.Output)
+}
+
+// sourcesDirectory returns the directory of the human-readable files of a pack: the one configured for the pack, or a
+// directory inside the sources root, nested in directories matching its pack folders.
+func sourcesDirectory(root string, name string, folders []string) string {
+	if s := cfg.Pack(name).Sources; s != "" {
+		return resolvePath(root, s)
+	}
+
+	return filepath.Join(append(append([]string{sourcesRoot(root)}, folders...), name)...)
+}
+
+// resolvePath resolves a path relative to the root of the module or system.
+func resolvePath(root string, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+
+	return filepath.Join(root, p)
+}
+
+// folderDirectory returns the directory name of a pack folder.
+func folderDirectory(name string) string {
+	return strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+}
+
+// addPacksFlags defines the flags used to locate the LevelDB packs and their human-readable files.
+func addPacksFlags(c *cobra.Command) {
+	c.Flags().StringP("path", "p", "", "Path of the module or system directory")
+	c.Flags().StringP("directory", "d", "packs", "Directory containing LevelDB packs when there is no manifest")
+	c.Flags().StringP("output", "o", "_pack_sources", "Directory of the human-readable files, relative to the module or system directory")
+}
+
+// projectRoot returns the root directory of the module or system, given by the path flag, or the directory of the
+// project config file, or the current working directory.
+func projectRoot(cmd *cobra.Command) (string, error) {
+	p, _ := cmd.Flags().GetString("path")
+	if p != "" {
+		return p, nil
+	}
+	if projectConfigDir != "" {
+		return projectConfigDir, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", errors.New("cannot get the current working directory")
+	}
+
+	return cwd, nil
+}
+
+// packsDirectory returns the directory containing the LevelDB packs, according to the path and directory flags.
+func packsDirectory(cmd *cobra.Command) (string, error) {
+	p, err := projectRoot(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	d := cfg.Directory
+	pd := filepath.Join(p, d)
+
+	info, err := os.Stat(pd)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no directory \"%s\" found\n", d)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot access directory \"%s\": %s\n", d, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("\"%s\" is not a directory\n", d)
+	}
+
+	return pd, nil
+}
+
+// loadManifest returns the manifest of the project, or nil if the project has none.
+func loadManifest(cmd *cobra.Command) (*manifest.Manifest, error) {
+	p, err := projectRoot(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := manifest.Load(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return m, err
+}
+
+// declaredPacks returns the packs of the project, whether their database exists or not. They are read from the
+// module.json or system.json manifest when there is one. Otherwise, every directory of the packs directory is
+// considered as a pack.
+func declaredPacks(cmd *cobra.Command) ([]packInfo, *manifest.Manifest, error) {
+	m, err := loadManifest(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m == nil {
+		root, err := projectRoot(cmd)
+		if err != nil {
+			return nil, nil, err
+		}
+		pd, err := packsDirectory(cmd)
+		if err != nil {
+			return nil, nil, err
+		}
+		packs, err := directoryPacks(root, pd)
+		return packs, nil, err
+	}
+
+	root := filepath.Dir(m.File)
+	var packs []packInfo
+	for _, p := range m.Packs {
+		info := packInfo{name: p.Name, path: filepath.Join(root, p.DatabasePath())}
+		for _, f := range m.FolderPath(p.Name) {
+			info.folders = append(info.folders, folderDirectory(f))
+		}
+		info.sources = sourcesDirectory(root, p.Name, info.folders)
+
+		if c, ok := documents.CollectionName(p.Type); ok {
+			info.collection = c
+		} else {
+			fmt.Printf("warning: pack %s has an unknown type \"%s\"\n", p.Name, p.Type)
+		}
+
+		packs = append(packs, info)
+	}
+
+	return packs, m, nil
+}
+
+// discoverPacks returns the packs of the project having a database. When they are read from the manifest, it warns
+// about packs without database and databases missing from the manifest.
+func discoverPacks(cmd *cobra.Command) ([]packInfo, *manifest.Manifest, error) {
+	declared, m, err := declaredPacks(cmd)
+	if err != nil || m == nil {
+		return declared, m, err
+	}
+
+	paths := map[string]bool{}
+	var packs []packInfo
+	for _, p := range declared {
+		paths[p.path] = true
+		if s, err := os.Stat(p.path); err != nil || !s.IsDir() {
+			fmt.Printf("warning: pack %s is declared in %s but has no database at %s\n", p.name, filepath.Base(m.File), p.path)
+			continue
+		}
+
+		packs = append(packs, p)
+	}
+
+	// Only warn about the undeclared databases: a pack missing from the manifest is not loaded by Foundry.
+	if pd, err := packsDirectory(cmd); err == nil {
+		others, err := directoryPacks(filepath.Dir(m.File), pd)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, o := range others {
+			if !paths[o.path] {
+				fmt.Printf("warning: database %s is not declared in %s\n", filepath.Join(filepath.Base(pd), o.name), filepath.Base(m.File))
+			}
+		}
+	}
+
+	return packs, m, nil
+}
+
+// findPack returns the pack of the project having the given name and a database.
+func findPack(cmd *cobra.Command, name string) (packInfo, error) {
+	packs, _, err := discoverPacks(cmd)
+	if err != nil {
+		return packInfo{}, err
+	}
+
+	for _, p := range packs {
+		if p.name == name {
+			return p, nil
+		}
+	}
+
+	return packInfo{}, fmt.Errorf("no pack \"%s\" found\n", name)
+}
+
+// selectPacks returns the packs having the given names, or every pack if no name is given. The packs are the ones
+// having a database, or the ones having sources when fromSources is true.
+func selectPacks(cmd *cobra.Command, names []string, fromSources bool) ([]packInfo, error) {
+	var packs []packInfo
+	var err error
+	if fromSources {
+		packs, err = sourcePacks(cmd)
+	} else {
+		packs, _, err = discoverPacks(cmd)
+	}
+	if err != nil || len(names) == 0 {
+		return packs, err
+	}
+
+	var selected []packInfo
+	for _, name := range names {
+		found := false
+		for _, p := range packs {
+			if p.name == name {
+				selected = append(selected, p)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no pack \"%s\" found\n", name)
+		}
+	}
+
+	return selected, nil
+}
+
+// packDocuments returns the primary documents of a pack, with their _key and their embedded documents, read from its
+// database, or from its sources when fromSources is true.
+func packDocuments(pack packInfo, fromSources bool) ([]map[string]interface{}, error) {
+	if fromSources {
+		entries, err := readPackSources(pack)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return packer.Hydrate(entries), nil
+	}
+
+	var entries []packer.Entry
+	err := eachPackEntry(pack.path, func(key string, collection string, id string, v interface{}) error {
+		if value, ok := v.(map[string]interface{}); ok {
+			entries = append(entries, packer.Entry{Key: key, Value: value, File: pack.path})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return packer.Hydrate(entries), nil
+}
+
+// directoryPacks returns every directory of the given packs directory as a pack of the module or system at root.
+func directoryPacks(root string, pd string) ([]packInfo, error) {
+	entries, err := os.ReadDir(pd)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read directory \"%s\": %s\n", pd, err)
+	}
+
+	var packs []packInfo
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		packs = append(packs, packInfo{name: e.Name(), path: filepath.Join(pd, e.Name()), sources: sourcesDirectory(root, e.Name(), nil)})
+	}
+
+	return packs, nil
+}
+
+// eachPackEntry calls fn with the decoded value of every entry of the given LevelDB pack.
+func eachPackEntry(path string, fn func(key string, collection string, id string, v interface{}) error) error {
+	db, err := fvttdb.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open db: %s\n", err)
+	}
+	defer db.Close()
+
+	return db.IterateAll(func(iter iterator.Iterator) error {
+		key := string(iter.Key())
+		parts := strings.Split(key, "!")
+		if len(parts) < 3 {
+			return nil
+		}
+
+		v, err := docpath.Decode(iter.Value())
+		if err != nil {
+			return fmt.Errorf("cannot decode entry: %s\n", err)
+		}
+
+		return fn(key, parts[1], parts[2], v)
+	})
+}
+
+// rewritePackEntries calls fn with the decoded value of every entry of the given LevelDB pack. fn modifies the value in
+// place and reports whether it changed. The modified entries are then saved unless dryRun is true. It returns the number
+// of modified entries.
+func rewritePackEntries(path string, dryRun bool, fn func(key string, v interface{}) bool) (int, error) {
+	updates := map[string][]byte{}
+
+	err := eachPackEntry(path, func(key string, collection string, id string, v interface{}) error {
+		if !fn(key, v) {
+			return nil
+		}
+
+		data, err := docpath.Encode(v)
+		if err != nil {
+			return fmt.Errorf("cannot encode entry: %s\n", err)
+		}
+		updates[key] = data
+
+		return nil
+	})
+	if err != nil || dryRun || len(updates) == 0 {
+		return len(updates), err
+	}
+
+	db, err := fvttdb.OpenForWrite(path)
+	if err != nil {
+		return 0, fmt.Errorf("cannot open db: %s\n", err)
+	}
+	defer db.Close()
+
+	for key, data := range updates {
+		if err := db.Put(key, data); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(updates), nil
+}
+
+// setEmbeddedKeys gives a _key to the embedded documents of the document having none, e.g. the effects of an item given
+// by a template or added by a patch. Those having no _id either are given the one newId returns from the id of their
+// parent, their collection and their position. It fails if an embedded collection holds something else than documents.
+func setEmbeddedKeys(doc map[string]interface{}, newId func(parentId string, field string, index int) string) error {
+	key, _ := doc["_key"].(string)
+	parts := strings.Split(key, "!")
+	if len(parts) < 3 {
+		return nil
+	}
+	parentId := parts[2][strings.LastIndex(parts[2], ".")+1:]
+
+	for field, v := range doc {
+		children, ok := v.([]interface{})
+		if _, known := documents.DocumentName(field); !ok || !known {
+			continue
+		}
+		for i, c := range children {
+			child, ok := c.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("element %d of %s is not a document", i, field)
+			}
+			if _, exists := child["_key"]; !exists {
+				id, ok := child["_id"].(string)
+				if !ok || id == "" {
+					id = newId(parentId, field, i)
+					child["_id"] = id
+				}
+				child["_key"] = "!" + parts[1] + "." + field + "!" + parts[2] + "." + id
+			}
+			if err := setEmbeddedKeys(child, newId); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
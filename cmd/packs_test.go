@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+func TestSetEmbeddedKeys(t *testing.T) {
+	tests := []struct {
+		doc   string
+		keys  map[string]string
+		error string
+	}{
+		{
+			doc: `{"_key": "!actors!aaaaaaaaaaaaaaaa", "items": [
+				{"_id": "bbbbbbbbbbbbbbbb", "_key": "!actors.items!aaaaaaaaaaaaaaaa.bbbbbbbbbbbbbbbb"},
+				{"_id": "cccccccccccccccc", "name": "Club"}]}`,
+			keys: map[string]string{
+				"items.0": "!actors.items!aaaaaaaaaaaaaaaa.bbbbbbbbbbbbbbbb",
+				"items.1": "!actors.items!aaaaaaaaaaaaaaaa.cccccccccccccccc",
+			},
+		},
+		{
+			doc: `{"_key": "!actors!aaaaaaaaaaaaaaaa", "items": [{"name": "Club"}, {"name": "Dagger", "_id": ""}]}`,
+			keys: map[string]string{
+				"items.0": "!actors.items!aaaaaaaaaaaaaaaa.aaaaaaaaaaitems0",
+				"items.1": "!actors.items!aaaaaaaaaaaaaaaa.aaaaaaaaaaitems1",
+			},
+		},
+		{
+			doc: `{"_key": "!actors!aaaaaaaaaaaaaaaa", "items": [
+				{"_id": "bbbbbbbbbbbbbbbb", "_key": "!actors.items!aaaaaaaaaaaaaaaa.bbbbbbbbbbbbbbbb",
+					"effects": [{"_id": "dddddddddddddddd"}, {"name": "Glow"}]}]}`,
+			keys: map[string]string{
+				"items.0.effects.0": "!actors.items.effects!aaaaaaaaaaaaaaaa.bbbbbbbbbbbbbbbb.dddddddddddddddd",
+				"items.0.effects.1": "!actors.items.effects!aaaaaaaaaaaaaaaa.bbbbbbbbbbbbbbbb.bbbbbbbbeffects1",
+			},
+		},
+		{
+			doc:  `{"_key": "!actors!aaaaaaaaaaaaaaaa", "system": {"items": [{"name": "Club"}]}, "tags": [{"a": 1}]}`,
+			keys: map[string]string{"system.items.0": "", "tags.0": ""},
+		},
+		{
+			doc:  `{"name": "No key", "items": [{"name": "Club"}]}`,
+			keys: map[string]string{"items.0": ""},
+		},
+		{
+			doc:   `{"_key": "!actors!aaaaaaaaaaaaaaaa", "items": [{"name": "Club"}, "Dagger"]}`,
+			error: "element 1 of items is not a document",
+		},
+	}
+
+	// newId gives ids showing what they are made of, e.g. aaaaaaaaaaitems0 for the first item of aaaaaaaaaaaaaaaa.
+	newId := func(parentId string, field string, index int) string {
+		id := fmt.Sprintf("%s%d", field, index)
+		return parentId[:16-len(id)] + id
+	}
+
+	for _, tt := range tests {
+		v, err := docpath.Decode([]byte(tt.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := v.(map[string]interface{})
+
+		err = setEmbeddedKeys(doc, newId)
+		if tt.error != "" {
+			if err == nil || err.Error() != tt.error {
+				t.Errorf("setEmbeddedKeys(%s) = %v, expected %s", tt.doc, err, tt.error)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("setEmbeddedKeys(%s): %s", tt.doc, err)
+			continue
+		}
+
+		for path, expected := range tt.keys {
+			child, _ := docpath.Get(doc, path)
+			obj, _ := child.(map[string]interface{})
+			key, _ := obj["_key"].(string)
+			if key != expected {
+				t.Errorf("setEmbeddedKeys(%s) gives the _key %q to %s, expected %q", tt.doc, key, path, expected)
+			}
+			if id, _ := obj["_id"].(string); expected != "" && key[len(key)-16:] != id {
+				t.Errorf("setEmbeddedKeys(%s) gives the _id %q to %s, not matching its _key", tt.doc, id, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,242 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/djlechuck/fvtt-packs/internal/query"
+	"github.com/djlechuck/fvtt-packs/internal/serializer"
+	"github.com/djlechuck/fvtt-packs/internal/sheet"
+	"github.com/spf13/cobra"
+)
+
+// queryCellWidth is the maximum number of characters of the cells of the query results printed as a table.
+const queryCellWidth = 60
+
+// queryResult is a value given by a query for a document.
+type queryResult struct {
+	pack  string
+	doc   map[string]interface{}
+	value interface{}
+}
+
+// queryCmd represents the query command
+var queryCmd = &cobra.Command{
+	Use:   "query <expression> [pack...]",
+	Short: "Query the documents of the packs",
+	Long: `Evaluate an expression against every document of the given packs, or of every pack, and print the values it
+gives. The documents are read from the databases, or from the sources with the --sources flag, with their embedded
+documents, e.g. the items of the actors.
+
+The expression is written in a subset of the jq language (https://jqlang.github.io/jq/manual/):
+* paths: . (the document), .name, .system.price.value, .items[] (every item), .effects[0], ."my-field"
+* pipes (|), comparisons (==, !=, <, <=, >, >=), and, or, alternatives (.name // "none") and lists ([.items[].name])
+* objects: {name, type, ac: .system.attributes.ac.value}
+* negations: -1, -.system.bonus
+* the $pack variable, holding the name of the pack of the document
+* the functions not, length, keys, type, tostring, ascii_downcase, ascii_upcase, empty, any, all, map(f), select(f),
+  contains(s), has(key), startswith(s), endswith(s) and test(regexp)
+Unlike jq, a path going through a value of the wrong type gives nothing rather than an error, and select gives its
+input once even when its condition is true several times.
+
+The values are printed as a table, or as a JSON or YAML list with the --format flag. In a table, the documents are
+printed with their id, name and type, and the objects with a column per field.
+
+For example:
+
+fvtt-packs query 'select(.items[].name == "Longsword") | .name' monsters
+	Print the actors of the monsters pack owning a longsword.
+
+fvtt-packs query '.effects[] | select(.changes[].key == "system.attributes.ac.bonus") | {name, parent: ._key}'
+	Print the effects changing the armor class bonus, in every pack.
+
+fvtt-packs query 'select(.type == "weapon") | {$pack, name, price: .system.price.value}' --format json
+	Print the price of every weapon as JSON.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		q, err := query.Parse(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid expression: %s\n", err)
+		}
+
+		format, _ := cmd.Flags().GetString("format")
+		var encoder serializer.Format
+		if format != "table" {
+			if format != "json" && format != "yaml" {
+				return fmt.Errorf("unknown format %s, use table, json or yaml\n", format)
+			}
+			if encoder, err = serializer.Get(format); err != nil {
+				return err
+			}
+		}
+
+		fromSources, _ := cmd.Flags().GetBool("sources")
+		packs, err := selectPacks(cmd, args[1:], fromSources)
+		if err != nil {
+			return err
+		}
+
+		var results []queryResult
+		for _, pack := range packs {
+			docs, err := packDocuments(pack, fromSources)
+			if err != nil {
+				return err
+			}
+			for _, doc := range docs {
+				values, err := q.Run(doc, map[string]interface{}{"pack": pack.name})
+				if err != nil {
+					return fmt.Errorf("cannot evaluate the expression against %s: %s\n", doc["_key"], err)
+				}
+				for _, v := range values {
+					results = append(results, queryResult{pack: pack.name, doc: doc, value: v})
+				}
+			}
+		}
+
+		if encoder == nil {
+			printQueryTable(results, q.Columns())
+			return nil
+		}
+
+		values := make([]interface{}, len(results))
+		for i, r := range results {
+			values[i] = r.value
+		}
+		data, err := encoder.Encode(values)
+		if err != nil {
+			return fmt.Errorf("cannot encode the results: %s\n", err)
+		}
+		fmt.Println(strings.TrimRight(string(data), "\n"))
+
+		return nil
+	},
+}
+
+// printQueryTable prints the results of a query as a table, the objects having a column per field, ordered as the
+// given columns if any.
+func printQueryTable(results []queryResult, columns []string) {
+	rows := make([]map[string]string, len(results))
+	known := map[string]bool{"pack": true}
+	var fields []string
+	withDocument := false
+	for i, r := range results {
+		row := map[string]string{"pack": r.pack}
+		obj, isObject := r.value.(map[string]interface{})
+		_, isDocument := obj["_key"]
+		if !isDocument || reflect.ValueOf(obj).Pointer() != reflect.ValueOf(r.doc).Pointer() {
+			name, _ := r.doc["name"].(string)
+			row["document"] = name
+			withDocument = true
+		}
+
+		switch {
+		case isObject && isDocument:
+			for _, f := range []string{"_id", "name", "type"} {
+				row[f] = sheet.Cell(obj[f])
+			}
+		case isObject:
+			for f, v := range obj {
+				row[f] = sheet.Cell(v)
+			}
+		default:
+			row["value"] = sheet.Cell(r.value)
+		}
+
+		for f := range row {
+			if !known[f] && f != "document" {
+				known[f] = true
+				fields = append(fields, f)
+			}
+		}
+		rows[i] = row
+	}
+
+	header := []string{"pack"}
+	if withDocument {
+		header = append(header, "document")
+	}
+	header = append(header, orderColumns(fields, columns)...)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(header))
+		for i, h := range header {
+			cells[i] = shortenCell(row[h])
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+
+	fmt.Println(len(results), "results")
+}
+
+// orderColumns orders the fields of the results: first the given columns, then the fields of the documents, then the
+// others by name.
+func orderColumns(fields []string, columns []string) []string {
+	rank := map[string]int{}
+	for i, c := range append(columns, "_id", "name", "type") {
+		if _, ok := rank[c]; !ok {
+			rank[c] = i + 1
+		}
+	}
+	rank["value"] = len(rank) + 1
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		ri, rj := rank[fields[i]], rank[fields[j]]
+		if ri == 0 || rj == 0 {
+			if ri != rj {
+				return ri != 0
+			}
+			return fields[i] < fields[j]
+		}
+		return ri < rj
+	})
+
+	return fields
+}
+
+// shortenCell returns the cell on a single line, cut to queryCellWidth characters.
+func shortenCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= queryCellWidth {
+		return s
+	}
+
+	return string([]rune(s)[:queryCellWidth-1]) + "…"
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+
+	addPacksFlags(queryCmd)
+	queryCmd.Flags().Bool("sources", false, "Query the sources instead of the databases")
+	queryCmd.Flags().String("format", "table", "Format of the results: table, json or yaml")
+}
@@ -0,0 +1,169 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"github.com/djlechuck/fvtt-packs/internal/manifest"
+
+	"github.com/djlechuck/fvtt-packs/internal/release"
+	"github.com/spf13/cobra"
+)
+
+// defaultReleaseExcludes are the files never shipped in a release archive.
+var defaultReleaseExcludes = []string{".*", "node_modules"}
+
+// releaseCmd represents the release command
+var releaseCmd = &cobra.Command{
+	Use:   "release",
+	Short: "Build the release archive of the module or system",
+	Long: `Build a release: the sources of every pack are validated then packed, the version of the manifest is bumped
+and its download and manifest URLs are rewritten. Finally, the archive containing the manifest, the assets and the
+packs, without their LOCK and LOG files, is written in the output directory along with a copy of the manifest.
+
+URL templates can contain the {id} and {version} placeholders, for example:
+
+fvtt-packs release --bump minor \
+	--manifest-url "https://github.com/me/{id}/releases/latest/download/module.json" \
+	--download-url "https://github.com/me/{id}/releases/download/{version}/module.zip"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		packs, m, err := declaredPacks(cmd)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			return errors.New("no module.json or system.json manifest found")
+		}
+
+		version, _ := cmd.Flags().GetString("version")
+		if version == "" {
+			bump, _ := cmd.Flags().GetString("bump")
+			if version, err = release.BumpVersion(m.Version, bump); err != nil {
+				return err
+			}
+		}
+
+		built, err := readAndValidatePacks(packs)
+		if err != nil {
+			return err
+		}
+
+		var databases []string
+		root := filepath.Dir(m.File)
+		for i, pack := range packs {
+			rel, _ := filepath.Rel(root, pack.path)
+			databases = append(databases, rel)
+			if built[i] == nil {
+				continue
+			}
+
+			fmt.Println("packing", pack.name, "...")
+			if err := writePack(pack, built[i]); err != nil {
+				return err
+			}
+		}
+
+		m.Version = version
+		if t, _ := cmd.Flags().GetString("manifest-url"); t != "" {
+			m.ManifestURL = release.ExpandURL(t, m.Id, version)
+		}
+		if t, _ := cmd.Flags().GetString("download-url"); t != "" {
+			m.Download = release.ExpandURL(t, m.Id, version)
+		}
+
+		// The manifest is restored if the archive cannot be built, so that releasing again bumps the same version.
+		original, err := os.ReadFile(m.File)
+		if err != nil {
+			return fmt.Errorf("cannot read manifest: %s\n", err)
+		}
+		if err := m.Save(); err != nil {
+			return err
+		}
+
+		archive, err := buildArchive(cmd, m, packs, databases)
+		if err != nil {
+			if restoreErr := os.WriteFile(m.File, original, 0644); restoreErr != nil {
+				return fmt.Errorf("%scannot restore manifest: %s\n", err, restoreErr)
+			}
+			return err
+		}
+
+		fmt.Println("version", version, "released in", archive)
+
+		return nil
+	},
+}
+
+// buildArchive writes the archive of the release and a copy of the manifest into the dist directory. It returns the
+// path of the archive.
+func buildArchive(cmd *cobra.Command, m *manifest.Manifest, packs []packInfo, databases []string) (string, error) {
+	root := filepath.Dir(m.File)
+	output, _ := cmd.Flags().GetString("dist")
+	output = filepath.Join(root, output)
+	if err := os.MkdirAll(output, 0755); err != nil {
+		return "", fmt.Errorf("cannot create output directory: %s\n", err)
+	}
+
+	exclude, _ := cmd.Flags().GetStringSlice("exclude-files")
+	exclude = append(append(exclude, defaultReleaseExcludes...), filepath.Base(output))
+	if rel, err := filepath.Rel(root, sourcesRoot(root)); err == nil {
+		exclude = append(exclude, rel)
+	}
+	for _, pack := range packs {
+		if rel, err := filepath.Rel(root, pack.sources); err == nil {
+			exclude = append(exclude, rel)
+		}
+	}
+
+	kind := strings.TrimSuffix(filepath.Base(m.File), ".json")
+	archive := filepath.Join(output, kind+".zip")
+	if err := release.Zip(root, archive, exclude, databases); err != nil {
+		return "", err
+	}
+
+	manifestData, err := os.ReadFile(m.File)
+	if err != nil {
+		return "", fmt.Errorf("cannot read manifest: %s\n", err)
+	}
+	if err := os.WriteFile(filepath.Join(output, filepath.Base(m.File)), manifestData, 0644); err != nil {
+		return "", fmt.Errorf("cannot copy manifest: %s\n", err)
+	}
+
+	return archive, nil
+}
+
+func init() {
+	rootCmd.AddCommand(releaseCmd)
+
+	addPacksFlags(releaseCmd)
+	releaseCmd.Flags().String("bump", "patch", "Part of the version to bump: major, minor or patch")
+	releaseCmd.Flags().String("version", "", "Version of the release, instead of bumping the current one")
+	releaseCmd.Flags().String("manifest-url", "", "Template of the manifest URL")
+	releaseCmd.Flags().String("download-url", "", "Template of the download URL")
+	releaseCmd.Flags().String("dist", "dist", "Directory where the archive is written, relative to the module or system")
+	releaseCmd.Flags().StringSlice("exclude-files", nil, "Additional files or directories to leave out of the archive")
+}
@@ -0,0 +1,82 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/djlechuck/fvtt-packs/internal/rename"
+	"github.com/spf13/cobra"
+)
+
+// renameModuleCmd represents the rename-module command
+var renameModuleCmd = &cobra.Command{
+	Use:   "rename-module <old> <new>",
+	Short: "Replace a module id by another one in all the packs",
+	Long: `Replace a module id by another one everywhere it appears inside the packs:
+* modules/<old>/ asset paths
+* Compendium.<old>. UUIDs, inside links or fields like _stats.compendiumSource
+* flags.<old> namespaces, including keys of active effect changes
+
+The manifest itself is not modified. Use it when forking a module or moving content from a system to a module, then
+unpack the packs again to update your sources.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		old, new := args[0], args[1]
+
+		packs, _, err := discoverPacks(cmd)
+		if err != nil {
+			return err
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		for _, pack := range packs {
+			pName := pack.name
+			count, err := rewritePackEntries(pack.path, dryRun, func(key string, v interface{}) bool {
+				n := rename.Module(v, old, new)
+				if n > 0 {
+					fmt.Printf("%s %s: %d replacements\n", pName, key, n)
+				}
+
+				return n > 0
+			})
+			if err != nil {
+				return err
+			}
+
+			if count > 0 {
+				fmt.Println(pName, ":", count, "entries updated")
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renameModuleCmd)
+
+	addPacksFlags(renameModuleCmd)
+	renameModuleCmd.Flags().Bool("dry-run", false, "Only report the entries which would be modified")
+}
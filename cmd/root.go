@@ -23,15 +23,28 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/djlechuck/fvtt-packs/internal/config"
+	"github.com/djlechuck/fvtt-packs/internal/exclude"
+	"github.com/djlechuck/fvtt-packs/internal/manifest"
+	"github.com/djlechuck/fvtt-packs/internal/richtext"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
 
+// cfg is the configuration of the running command, loaded before it runs.
+var cfg *config.Config
+
+// projectConfigDir is the directory of the project configuration file, empty when there is none.
+var projectConfigDir string
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "fvtt-packs",
@@ -44,12 +57,24 @@ You can call this utility from everywhere. Go into your system/module directory
 For example:
 
 fvtt-packs unpack
-	This will unpack all the LevelDB which are in the packs directory into a _packs_sources directory, containing human-readable files.
+	This will unpack all the LevelDB which are in the packs directory into a _pack_sources directory, containing human-readable files.
 
 fvtt-packs pack
-	This will pack all the human-readable files inside _packs_sources directory into the packs directory.
-
-Flags can be used to customize the tools.`,
+	This will pack all the human-readable files inside _pack_sources directory into the packs directory.
+
+Flags can be used to customize the tools. They can also be set once for all in a .fvtt-packs.yaml file at the root of
+your system/module, or with FVTT_PACKS_* environment variables, e.g.:
+
+format: yaml
+directory: packs
+output: _pack_sources
+packs:
+  journals:
+    format: json
+    sources: src/journals`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return initConfig(cmd)
+	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
@@ -65,24 +90,30 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
-
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.fvtt-packs.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is the .fvtt-packs.yaml of the project or of $HOME)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
+// initConfig reads in config file and ENV variables if set, then loads the configuration of the command.
+func initConfig(cmd *cobra.Command) error {
+	projectConfigDir = ""
 	if cfgFile != "" {
 		// Use config file from the flag.
 		viper.SetConfigFile(cfgFile)
+	} else if file, ok := config.Find(configSearchDirectory(cmd)); ok {
+		// Use the project config file, found in the module directory or one of its parents. Its directory is the root
+		// of the project only when it holds the manifest, a file found higher only giving settings.
+		viper.SetConfigFile(file)
+		if manifest.Exists(filepath.Dir(file)) {
+			projectConfigDir = filepath.Dir(file)
+		}
 	} else {
 		// Find home directory.
 		home, err := os.UserHomeDir()
@@ -94,10 +125,49 @@ func initConfig() {
 		viper.SetConfigName(".fvtt-packs")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	// Read in environment variables that match, e.g. FVTT_PACKS_FORMAT for the format setting.
+	viper.SetEnvPrefix(config.EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
+
+	viper.SetDefault("format", "json")
+	viper.SetDefault("directory", "packs")
+	viper.SetDefault("output", "_pack_sources")
+	viper.SetDefault("exclude", exclude.Defaults)
+	viper.SetDefault("htmlFields", richtext.DefaultFields)
+	if err := viper.BindPFlags(cmd.Flags()); err != nil {
+		return err
+	}
 
-	// If a config file is found, read it in.
+	// If a config file is found, read it in. Only a missing file is ignored.
+	var notFound viper.ConfigFileNotFoundError
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	} else if !errors.As(err, &notFound) && !missingFile(viper.ConfigFileUsed()) {
+		return fmt.Errorf("cannot read config file %s: %s\n", viper.ConfigFileUsed(), err)
 	}
+
+	var err error
+	cfg, err = config.Load(cmd.Flags())
+
+	return err
+}
+
+// missingFile reports whether the given file does not exist.
+func missingFile(file string) bool {
+	_, err := os.Stat(file)
+
+	return errors.Is(err, os.ErrNotExist)
+}
+
+// configSearchDirectory returns the directory from which the project config file is searched.
+func configSearchDirectory(cmd *cobra.Command) string {
+	if f := cmd.Flags().Lookup("path"); f != nil && f.Value.String() != "" {
+		return f.Value.String()
+	}
+
+	cwd, err := os.Getwd()
+	cobra.CheckErr(err)
+
+	return cwd
 }
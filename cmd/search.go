@@ -0,0 +1,94 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/djlechuck/fvtt-packs/internal/search"
+	"github.com/spf13/cobra"
+)
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search <words...>",
+	Short: "Search for words in the packs",
+	Long: `Search for words in the names and the rich-text fields of the documents of every pack, or of the packs given
+with the --pack flag, e.g. the descriptions of the items, the journal pages or the results of the roll tables. The
+documents are read from the databases, or from the sources with the --sources flag. The rich-text fields are the ones
+of the htmlFields setting (see the unpack command).
+
+A field matches when it contains every word, case and diacritics being ignored. Words between double quotes must
+follow each other. The fields are ranked by relevance, matching names first, and printed with their pack, document and
+a snippet of their text.
+
+For example:
+
+fvtt-packs search '"magic missile"' force
+	Print the fields containing "magic missile" and "force".`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, _ := cmd.Flags().GetStringSlice("pack")
+		fromSources, _ := cmd.Flags().GetBool("sources")
+		limit, _ := cmd.Flags().GetInt("limit")
+		packs, err := selectPacks(cmd, names, fromSources)
+		if err != nil {
+			return err
+		}
+
+		index := search.NewIndex(cfg.HtmlFields)
+		for _, pack := range packs {
+			docs, err := packDocuments(pack, fromSources)
+			if err != nil {
+				return err
+			}
+			for _, doc := range docs {
+				index.AddDocument(pack.name, doc)
+			}
+		}
+
+		results := index.Search(strings.Join(args, " "), limit)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "pack\tdocument\tfield\tsnippet")
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Pack, shortenCell(r.Document), r.Path, r.Snippet)
+		}
+		w.Flush()
+
+		fmt.Println(len(results), "results")
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	addPacksFlags(searchCmd)
+	searchCmd.Flags().StringSlice("pack", nil, "Only search these packs")
+	searchCmd.Flags().Int("limit", 20, "Maximum number of results, 0 for no limit")
+	searchCmd.Flags().Bool("sources", false, "Search the sources instead of the databases")
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2024 DjLeChuck <djlechuck@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/djlechuck/fvtt-packs/internal/sqlexport"
+	"github.com/spf13/cobra"
+)
+
+// exportSqliteCmd represents the export-sqlite command
+var exportSqliteCmd = &cobra.Command{
+	Use:   "export-sqlite <file>",
+	Short: "Export the documents of all the packs into a SQLite database",
+	Long: `Export the documents of all the packs into a SQLite database, to query them with SQL. The file is replaced if
+it exists.
+
+Each collection has its table, e.g. "items" for the items of every pack, "actors" for the actors, "journal" for the
+journals and "folders" for the folders. The tables of the embedded documents are named after their parent, e.g.
+"actors_items" for the items of the actors, "items_effects" for the effects of the items or "journal_pages" for the
+pages of the journals. Every table has the pack, _id, name, type, folder and img columns, and the data column holding
+the document as JSON, as stored in the pack, to be read with the JSON functions of SQLite. The tables of embedded documents also have a
+column for the id of their parent, e.g. "actor_id", which is a foreign key.
+
+For example:
+
+fvtt-packs export-sqlite packs.db
+sqlite3 packs.db "SELECT pack, name, data ->> '$.system.damage.parts[0][0]' FROM items WHERE type = 'weapon'"
+	List the damage of every weapon.
+
+sqlite3 packs.db "SELECT a.name, i.name FROM actors a JOIN actors_items i ON i.pack = a.pack AND i.actor_id = a._id"
+	List the items of every actor.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		packs, _, err := discoverPacks(cmd)
+		if err != nil {
+			return err
+		}
+
+		exporter, err := sqlexport.Create(args[0])
+		if err != nil {
+			return fmt.Errorf("cannot create %s: %s\n", args[0], err)
+		}
+
+		count := 0
+		for _, pack := range packs {
+			fmt.Println("exporting", pack.name, "...")
+			err := eachPackEntry(pack.path, func(key string, collection string, id string, v interface{}) error {
+				count++
+				if err := exporter.Add(pack.name, key, v); err != nil {
+					return fmt.Errorf("cannot export %s: %s\n", key, err)
+				}
+				return nil
+			})
+			if err != nil {
+				exporter.Abort()
+				return err
+			}
+		}
+
+		if err := exporter.Close(); err != nil {
+			return fmt.Errorf("cannot write %s: %s\n", args[0], err)
+		}
+		fmt.Println(count, "documents exported into", args[0])
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportSqliteCmd)
+
+	addPacksFlags(exportSqliteCmd)
+}
@@ -23,14 +23,18 @@ THE SOFTWARE.
 package cmd
 
 import (
-	"errors"
 	"fmt"
 	"github.com/djlechuck/fvtt-packs/internal/documents"
+	"github.com/djlechuck/fvtt-packs/internal/exclude"
 	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
+	"github.com/djlechuck/fvtt-packs/internal/richtext"
 	"github.com/djlechuck/fvtt-packs/internal/serializer"
+	"github.com/djlechuck/fvtt-packs/internal/sparse"
 	"github.com/spf13/cobra"
 	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -41,57 +45,143 @@ var unpackCmd = &cobra.Command{
 	Short: "Unpack LevelDB into human-readable files",
 	Long: `Unpack the LevelDB to get human-readable files. Multiple output formats are supported:
 * JSON (default)
-* YAML (with -y flag)
+* YAML (with -y flag, or --format yaml)
+* TOML (with --format toml), where null values are written as {"$null" = true}
 
-By default, packs are inside a packs directory. If this is not the case, you can override it with the -d flag: fvtt-packs unpack -d mypacks`,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		p, _ := cmd.Flags().GetString("path")
-		if p == "" {
-			cwd, err := os.Getwd()
-			if err != nil {
-				return errors.New("cannot get the current working directory")
-			}
-			p = cwd
-		}
+The format can also be set per pack in the project config file.
 
-		d, _ := cmd.Flags().GetString("directory")
-		pd := filepath.Join(p, d)
+Files are named after the {name}_{id} template by default. Use the --filename flag, or the filename setting of the
+project config file, to change it: {name}, {id} and {type} are replaced by the name, id and type of the document, and
+slashes create subdirectories, e.g. --filename "{type}/{name}". Names are transliterated to ASCII when possible.
 
-		info, err := os.Stat(pd)
-		if os.IsNotExist(err) {
-			return fmt.Errorf("no directory \"%s\" found\n", d)
-		}
+Fields changing on every edit in Foundry are left out of the files, so that the diffs only show content changes:
+_stats.coreVersion, _stats.lastModifiedBy, _stats.modifiedTime, _stats.systemVersion and the ownership given to each
+user. The exclude setting of the project config file changes this list, where * matches any key and {id} any id:
+
+exclude:
+  - _stats.modifiedTime
+  - ownership.{id}
+
+A pack can have its own exclude setting, inside the packs setting, which replaces this list.
+
+With the --html flag, or the html setting of the project config file, the rich-text fields are written into .html
+files next to their document, with a line break after each block. The document references them as
+{"$file": "<document>.<field>.html"}, e.g. {"$file": "Dagger_id.system.description.value.html"}, the id of an
+embedded document following the name of the document file. The htmlFields setting gives the fields by document type:
+
+htmlFields:
+  Item:
+    - system.description.value
+    - system.unidentified.description
+
+With the --markdown flag, or the markdown setting of the project config file, the rich-text fields are converted to
+Markdown files instead, the enrichers like @UUID[...]{...} or [[/r 1d20]] being kept as they are. The text pages of the
+journals are written into their own Markdown file, e.g. Lore_id.pageId.md, their other fields being in its front
+matter. Pages already written in Markdown in Foundry are kept as they are. Note that converting HTML to Markdown and
+back can change the markup, e.g. unsupported tags are dropped.
+
+With the --sparse flag, or the sparse setting of the project config file, the fields having the default value of
+Foundry are left out of the files, e.g. the sight, light or ring of the tokens which have not been changed. They are
+filled back in by pack.
+
+With the --canonical flag, or the canonical setting of the project config file, files are written in a canonical form:
+keys are sorted, numbers are written in their shortest form, null lists and objects are written as empty ones, and YAML
+files use block scalars for multi-line strings. Unpacking unchanged packs then gives the exact same files, whatever the
+way the documents have been edited in Foundry.
+
+With the --layout folders flag, or the layout setting of the project config file, files are written in directories
+mirroring the folders of the pack. Each directory holds the metadata of its folder in a _folder file.
+
+With the --layout jsonl flag, each pack is written into a single JSON Lines file, e.g. items/items.jsonl, holding one
+document per line, sorted by id. The format, filename, html and markdown settings do not apply.
+
+The files are written inside a _pack_sources directory of the module or system, which you can override with the -o
+flag: fvtt-packs unpack -o src/packs. The project config file can also give a directory per pack:
+
+packs:
+  items:
+    sources: src/items
+
+The packs are read from the module.json or system.json manifest of the current directory. Their sources are written
+in directories matching the packFolders of the manifest.
+
+Without manifest, packs are inside a packs directory. If this is not the case, you can override it with the -d flag:
+fvtt-packs unpack -d mypacks`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		packs, _, err := discoverPacks(cmd)
 		if err != nil {
-			return fmt.Errorf("cannot access directory \"%s\": %s\n", d, err)
-		}
-		if !info.IsDir() {
-			return fmt.Errorf("\"%s\" is not a directory\n", d)
+			return err
 		}
 
-		packs, err := os.ReadDir(pd)
-		if err != nil {
-			return fmt.Errorf("cannot read directory \"%s\": %s\n", pd, err)
+		if cmd.Flags().Changed("yaml") {
+			cfg.Force("format", "yaml")
 		}
 
-		isYaml, _ := cmd.Flags().GetBool("yaml")
+		keepStale, _ := cmd.Flags().GetBool("keep-stale")
+		others := map[string]bool{}
+		for _, pack := range packs {
+			others[pack.sources] = true
+		}
 
 		for _, pack := range packs {
-			pName := pack.Name()
-			if !pack.IsDir() {
-				fmt.Println(pName, "is not a directory")
-				continue
+			pName := pack.name
+			fmt.Println("unpacking", pName, "...")
+
+			packCfg := cfg.Pack(pName)
+			jsonLines := packCfg.Layout == "jsonl"
+			format, err := serializer.Get(packCfg.Format)
+			if err != nil {
+				return err
+			}
+			if jsonLines {
+				format = serializer.Line
 			}
+			// base is the name of the file being written, without extension, and sidecars the rich-text files
+			// extracted from its document.
+			var base string
+			var sidecars map[string]string
+			format = serializer.Transform(format, func(v interface{}) error {
+				exclude.Strip(v, packCfg.Exclude)
+				if cfg.Sparse {
+					sparse.Strip(v)
+				}
 
-			fmt.Println("unpacking", pName, "...")
+				var err error
+				switch {
+				case jsonLines:
+					// The rich-text fields stay inside the single file of the pack.
+				case cfg.Markdown:
+					sidecars, err = richtext.Extract(v, cfg.HtmlFields, base, richtext.MarkdownExtension)
+				case cfg.Html:
+					sidecars, err = richtext.Extract(v, cfg.HtmlFields, base, richtext.Extension)
+				}
 
-			fullPath := filepath.Join(pd, pack.Name())
+				return err
+			})
+			if cfg.Canonical {
+				format = serializer.Canonical(format)
+			}
 
-			db, err := fvttdb.Open(fullPath)
+			destination := pack.sources
+			produced := map[string]bool{}
+			namer := serializer.NewNamer(packCfg.Filename)
+
+			db, err := fvttdb.Open(pack.path)
 			if err != nil {
 				return fmt.Errorf("cannot open db: %s\n", err)
 			}
 			defer db.Close()
 
+			// lines are the documents of the JSON Lines file of the pack, by id.
+			lines := map[string][]byte{}
+
+			var tree *serializer.FolderTree
+			if packCfg.Layout == "folders" {
+				if tree, err = readFolderTree(db, pName); err != nil {
+					return err
+				}
+			}
+
 			err = db.IterateAll(func(iter iterator.Iterator) error {
 				k := iter.Key()
 				kStr := string(k)
@@ -104,6 +194,12 @@ By default, packs are inside a packs directory. If this is not the case, you can
 					return nil // This is not a primary document, skip it.
 				}
 
+				// Folders apart, the documents of a pack declared in the manifest are all of its type.
+				if pack.collection != "" && collection != "folders" && collection != pack.collection {
+					fmt.Printf("warning: %s is not a document of the %s type of the pack, skip it\n", kStr, pack.collection)
+					return nil
+				}
+
 				fmt.Println("processing", kStr)
 				doc, err := documents.Create(pName, collection, iter.Value())
 				if err != nil {
@@ -114,22 +210,158 @@ By default, packs are inside a packs directory. If this is not the case, you can
 					return fmt.Errorf("cannot hydrate doc collections: %s\n", err)
 				}
 
-				err = serializer.SerializeDocument(doc, "_pack_sources/"+pName, isYaml)
+				if jsonLines {
+					line, err := format.Encode(doc)
+					if err != nil {
+						return fmt.Errorf("cannot serialize doc: %s\n", err)
+					}
+					lines[(*doc).GetId()] = line
+
+					return nil
+				}
+
+				var name string
+				if tree != nil {
+					name = documentPath(tree, doc, namer, format)
+				} else {
+					name = namer.Name("", doc, format)
+				}
+
+				file := filepath.Join(destination, filepath.FromSlash(name))
+				base = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+				sidecars = nil
+				err = serializer.SerializeDocument(doc, file, format)
 				if err != nil {
 					return fmt.Errorf("cannot serialize doc: %s\n", err)
 				}
+				produced[file] = true
+
+				for sidecar, content := range sidecars {
+					sidecarFile := filepath.Join(filepath.Dir(file), sidecar)
+					if err := os.WriteFile(sidecarFile, []byte(content), 0644); err != nil {
+						return fmt.Errorf("cannot write rich-text file: %s\n", err)
+					}
+					produced[sidecarFile] = true
+				}
 
 				return nil
 			})
 			if err != nil {
 				return fmt.Errorf("iterator error: %s\n", err)
 			}
+
+			if jsonLines {
+				file := filepath.Join(destination, pName+serializer.LinesExtension)
+				if err := serializer.WriteLines(file, lines); err != nil {
+					return fmt.Errorf("cannot write %s: %s\n", file, err)
+				}
+				produced[file] = true
+			}
+
+			if err := pruneStaleFiles(destination, produced, others, keepStale); err != nil {
+				return fmt.Errorf("cannot prune stale files: %s\n", err)
+			}
 		}
 
 		return nil
 	},
 }
 
+// readFolderTree returns the directories mirroring the folders of the pack.
+func readFolderTree(db *fvttdb.FvttDb, pName string) (*serializer.FolderTree, error) {
+	var folders []*documents.FolderDocument
+	err := db.IterateAll(func(iter iterator.Iterator) error {
+		if !strings.HasPrefix(string(iter.Key()), "!folders!") {
+			return nil
+		}
+
+		doc, err := documents.Create(pName, "folders", iter.Value())
+		if err != nil {
+			return fmt.Errorf("cannot get folder: %s\n", err)
+		}
+		folders = append(folders, (*doc).(*documents.FolderDocument))
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read folders: %s\n", err)
+	}
+
+	return serializer.NewFolderTree(folders), nil
+}
+
+// documentPath returns the file name of a document, relative to the sources directory of its pack, when the files
+// mirror the folders: folders are written in the _folder file of their directory, other documents in the directory of
+// their folder.
+func documentPath(tree *serializer.FolderTree, doc *documents.Document, namer *serializer.Namer, format serializer.Format) string {
+	if f, ok := (*doc).(*documents.FolderDocument); ok {
+		return path.Join(tree.Directory(f.Id), serializer.FolderFileName+serializer.Extension(format))
+	}
+
+	folder := documents.FolderOf(*doc)
+	dir := tree.Directory(folder)
+	if folder != "" && dir == "" {
+		fmt.Printf("warning: %s is inside the unknown folder %s\n", (*doc).GetId(), folder)
+	}
+
+	return namer.Name(dir, doc, format)
+}
+
+// pruneStaleFiles removes the files of the sources directory of a pack which have not been produced by the unpacking,
+// as their document has been deleted or renamed, then the directories left empty. Hidden files and the sources
+// directories of other packs are left untouched. With keep, stale files are only reported.
+func pruneStaleFiles(dir string, produced map[string]bool, others map[string]bool, keep bool) error {
+	// An empty pack produces no sources directory.
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+
+	var dirs []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && p != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if p != dir && others[p] {
+				return filepath.SkipDir
+			}
+			dirs = append(dirs, p)
+			return nil
+		}
+		if produced[p] {
+			return nil
+		}
+
+		if keep {
+			fmt.Println("stale file", p)
+			return nil
+		}
+
+		fmt.Println("removing stale file", p)
+		return os.Remove(p)
+	})
+	if err != nil || keep {
+		return err
+	}
+
+	// Remove the deepest directories first, so that their parents can be empty too.
+	for i := len(dirs) - 1; i > 0; i-- {
+		if entries, err := os.ReadDir(dirs[i]); err == nil && len(entries) == 0 {
+			if err := os.Remove(dirs[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(unpackCmd)
 
@@ -141,7 +373,14 @@ func init() {
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
-	unpackCmd.Flags().StringP("path", "p", "", "Path of the directory containing LevelDB packs")
-	unpackCmd.Flags().StringP("directory", "d", "packs", "Directory containing LevelDB packs")
+	addPacksFlags(unpackCmd)
 	unpackCmd.Flags().BoolP("yaml", "y", false, "Unpack as YAML files instead of JSON")
+	unpackCmd.Flags().String("format", "json", "Format of the unpacked files: "+strings.Join(serializer.Names(), ", "))
+	unpackCmd.Flags().String("filename", documents.DefaultFilename, "Template of the file names, using {name}, {id} and {type}")
+	unpackCmd.Flags().String("layout", "flat", "Layout of the files: flat, folders to mirror the folders of the pack, or jsonl for a single file")
+	unpackCmd.Flags().Bool("html", false, "Write the rich-text fields into .html files next to their document")
+	unpackCmd.Flags().Bool("markdown", false, "Write the rich-text fields into Markdown files next to their document")
+	unpackCmd.Flags().Bool("sparse", false, "Leave out the fields having the default value of Foundry")
+	unpackCmd.Flags().Bool("canonical", false, "Write the files in a canonical form, so that unpacking unchanged packs gives no diff")
+	unpackCmd.Flags().Bool("keep-stale", false, "Only report the files which are not backed by a document anymore instead of removing them")
 }
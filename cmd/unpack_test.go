@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
+)
+
+// sceneEntries are the LevelDB entries of a scene pack written by Foundry 12: a scene with a token, a wall and a
+// region having a behavior.
+var sceneEntries = map[string]string{
+	"!scenes!s000000000000001": `{"_id": "s000000000000001", "name": "Crypt", "active": false, "navigation": true,
+		"navOrder": 0, "navName": "", "foreground": null, "width": 4000, "height": 3000, "padding": 0.25,
+		"backgroundColor": "#999999", "tokenVision": true, "weather": "", "folder": null, "sort": 0,
+		"fog": {"exploration": true, "overlay": null, "colors": {"explored": null, "unexplored": null}},
+		"environment": {"darknessLevel": 0.5, "darknessLock": false, "globalLight": {"enabled": false}},
+		"drawings": [], "tokens": ["t000000000000001"], "lights": [], "notes": [], "sounds": [],
+		"regions": ["r000000000000001"], "templates": [], "tiles": [], "walls": ["w000000000000001"],
+		"flags": {}, "ownership": {"default": 0}}`,
+	"!scenes.tokens!s000000000000001.t000000000000001": `{"_id": "t000000000000001", "name": "Ghoul",
+		"actorId": "a000000000000001", "x": 1200, "y": 800, "elevation": 0, "hidden": true, "width": 1, "height": 1,
+		"displayName": 0, "disposition": -1, "alpha": 1, "rotation": 0, "lockRotation": false,
+		"sight": {"enabled": false, "range": 0, "angle": 360, "visionMode": "basic", "color": null,
+			"attenuation": 0.1, "brightness": 0, "saturation": 0, "contrast": 0},
+		"ring": {"enabled": false, "colors": {"ring": null, "background": null}, "effects": 1,
+			"subject": {"scale": 1, "texture": null}},
+		"detectionModes": [], "flags": {}}`,
+	"!scenes.walls!s000000000000001.w000000000000001": `{"_id": "w000000000000001", "c": [0, 0, 100, 0],
+		"move": 20, "door": 1, "ds": 0, "flags": {}}`,
+	"!scenes.regions!s000000000000001.r000000000000001": `{"_id": "r000000000000001", "name": "Trap",
+		"shapes": [{"type": "rectangle", "x": 0, "y": 0, "width": 100, "height": 100}],
+		"behaviors": ["b000000000000001"], "flags": {}}`,
+	"!scenes.regions.behaviors!s000000000000001.r000000000000001.b000000000000001": `{"_id": "b000000000000001",
+		"name": "Spikes", "type": "executeScript", "system": {"source": "ui.notifications.info('ouch')"},
+		"disabled": false, "flags": {}}`,
+}
+
+func TestUnpackScenePack(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	manifest := `{"id": "my-module", "packs": [{"name": "scenes", "label": "Scenes", "path": "packs/scenes", "type": "Scene"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "module.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := fvttdb.Create(filepath.Join(dir, "packs", "scenes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range sceneEntries {
+		if err := db.Put(k, []byte(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	rootCmd.SetArgs([]string{"unpack", "-p", dir, "--sparse"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "_pack_sources", "scenes", "Crypt_s000000000000001.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scene map[string]interface{}
+	if err := json.Unmarshal(data, &scene); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, field := range []string{"active", "navigation", "fog", "foreground", "tokenVision"} {
+		if _, ok := scene[field]; ok {
+			t.Errorf("default field %s of the scene is not stripped", field)
+		}
+	}
+	env, _ := scene["environment"].(map[string]interface{})
+	if env["darknessLevel"] != 0.5 || env["globalLight"] != nil {
+		t.Errorf("environment is %v, expected only darknessLevel 0.5", env)
+	}
+
+	tokens, _ := scene["tokens"].([]interface{})
+	if len(tokens) != 1 {
+		t.Fatalf("scene has %d tokens, expected 1", len(tokens))
+	}
+	token := tokens[0].(map[string]interface{})
+	if token["_key"] != "!scenes.tokens!s000000000000001.t000000000000001" {
+		t.Errorf("token key is %v", token["_key"])
+	}
+	if token["x"] != 1200.0 || token["hidden"] != true {
+		t.Errorf("token lost its position or visibility: %v", token)
+	}
+	for _, field := range []string{"sight", "ring", "width", "disposition", "elevation"} {
+		if _, ok := token[field]; ok {
+			t.Errorf("default field %s of the token is not stripped", field)
+		}
+	}
+
+	walls, _ := scene["walls"].([]interface{})
+	if len(walls) != 1 || walls[0].(map[string]interface{})["door"] != 1.0 {
+		t.Errorf("walls are %v, expected the door", walls)
+	}
+	regions, _ := scene["regions"].([]interface{})
+	if len(regions) != 1 {
+		t.Fatalf("scene has %d regions, expected 1", len(regions))
+	}
+	behaviors, _ := regions[0].(map[string]interface{})["behaviors"].([]interface{})
+	if len(behaviors) != 1 || behaviors[0].(map[string]interface{})["_key"] != "!scenes.regions.behaviors!s000000000000001.r000000000000001.b000000000000001" {
+		t.Errorf("behaviors are %v, expected the spikes", behaviors)
+	}
+
+	rootCmd.SetArgs([]string{"pack", "-p", dir, "--sparse"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = fvttdb.Open(filepath.Join(dir, "packs", "scenes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for k := range sceneEntries {
+		if _, err := db.Get(k); err != nil {
+			t.Errorf("entry %s is not packed back", k)
+		}
+	}
+	packed, err := db.Get("!scenes.tokens!s000000000000001.t000000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token = map[string]interface{}{}
+	if err := json.Unmarshal(packed, &token); err != nil {
+		t.Fatal(err)
+	}
+	if sight, _ := token["sight"].(map[string]interface{}); sight["visionMode"] != "basic" {
+		t.Errorf("sight of the packed token is %v, expected its defaults", token["sight"])
+	}
+}
+
+func TestUnpackGenericPacks(t *testing.T) {
+	tests := []struct {
+		pack     string
+		docType  string
+		entries  map[string]string
+		file     string
+		embedded string
+	}{
+		{
+			pack:    "macros",
+			docType: "Macro",
+			entries: map[string]string{
+				"!macros!m000000000000001": `{"_id": "m000000000000001", "name": "Heal", "type": "script",
+					"command": "actor.heal()", "folder": null, "flags": {}}`,
+			},
+			file: "script/Heal.json",
+		},
+		{
+			pack:    "playlists",
+			docType: "Playlist",
+			entries: map[string]string{
+				"!playlists!p000000000000001": `{"_id": "p000000000000001", "name": "Tavern", "mode": 0,
+					"sounds": ["s000000000000001"], "flags": {}}`,
+				"!playlists.sounds!p000000000000001.s000000000000001": `{"_id": "s000000000000001",
+					"name": "Lute", "path": "music/lute.ogg", "volume": 0.5, "flags": {}}`,
+			},
+			file:     "p000000000000001/Tavern.json",
+			embedded: "sounds",
+		},
+		{
+			pack:    "cards",
+			docType: "Cards",
+			entries: map[string]string{
+				"!cards!c000000000000001": `{"_id": "c000000000000001", "name": "Tarot", "type": "deck",
+					"cards": ["d000000000000001"], "flags": {}}`,
+				"!cards.cards!c000000000000001.d000000000000001": `{"_id": "d000000000000001",
+					"name": "The Fool", "type": "base", "faces": [], "flags": {}}`,
+			},
+			file:     "deck/Tarot.json",
+			embedded: "cards",
+		},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		t.Setenv("HOME", t.TempDir())
+		manifest := `{"id": "my-module", "packs": [{"name": "` + tt.pack + `", "label": "L", "path": "packs/` + tt.pack +
+			`", "type": "` + tt.docType + `"}]}`
+		if err := os.WriteFile(filepath.Join(dir, "module.json"), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		db, err := fvttdb.Create(filepath.Join(dir, "packs", tt.pack))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range tt.entries {
+			if err := db.Put(k, []byte(v)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		db.Close()
+
+		rootCmd.SetArgs([]string{"unpack", "-p", dir, "--filename", "{type}/{name}"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("unpack %s: %s", tt.pack, err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "_pack_sources", tt.pack, filepath.FromSlash(tt.file)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if tt.embedded != "" {
+			children, _ := doc[tt.embedded].([]interface{})
+			if len(children) != 1 {
+				t.Fatalf("%s has %v as %s, expected 1 embedded document", tt.file, doc[tt.embedded], tt.embedded)
+			}
+			if _, ok := children[0].(map[string]interface{})["_key"]; !ok {
+				t.Errorf("embedded document of %s has no _key", tt.file)
+			}
+		}
+
+		rootCmd.SetArgs([]string{"pack", "-p", dir})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("pack %s: %s", tt.pack, err)
+		}
+
+		db, err = fvttdb.Open(filepath.Join(dir, "packs", tt.pack))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range tt.entries {
+			packed, err := db.Get(k)
+			if err != nil {
+				t.Errorf("entry %s is not packed back", k)
+				continue
+			}
+			var expected, got interface{}
+			_ = json.Unmarshal([]byte(v), &expected)
+			_ = json.Unmarshal(packed, &got)
+			e, _ := json.Marshal(expected)
+			g, _ := json.Marshal(got)
+			if string(e) != string(g) {
+				t.Errorf("entry %s is packed back as %s, expected %s", k, g, e)
+			}
+		}
+		db.Close()
+	}
+}
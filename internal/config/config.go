@@ -0,0 +1,157 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/manifest"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// FileName is the name of the project configuration file.
+const FileName = ".fvtt-packs.yaml"
+
+// EnvPrefix is the prefix of the environment variables overriding the configuration, e.g. FVTT_PACKS_FORMAT.
+const EnvPrefix = "FVTT_PACKS"
+
+// PackConfig holds the settings which can be overridden per pack.
+type PackConfig struct {
+	// Format is the format of the unpacked files: json or yaml.
+	Format string `mapstructure:"format"`
+	// Filename is the template of the file names of the unpacked documents.
+	Filename string `mapstructure:"filename"`
+	// Layout is the layout of the unpacked files: flat, folders to mirror the folders of the pack, or jsonl for a single
+	// JSON Lines file.
+	Layout string `mapstructure:"layout"`
+	// Sources is the directory of the unpacked files of the pack, relative to the module or system directory.
+	Sources string `mapstructure:"sources"`
+	// Exclude are the patterns of the fields left out of the unpacked files, and restored when packing. The patterns of
+	// a pack replace the global ones, an empty list excluding nothing.
+	Exclude []string `mapstructure:"exclude"`
+}
+
+// Config is the project configuration. Its settings come, by order of precedence, from the flags of the running
+// command, the FVTT_PACKS_* environment variables, the configuration file and the default values of the flags.
+type Config struct {
+	PackConfig `mapstructure:",squash"`
+	// Directory is the directory containing the LevelDB packs when there is no manifest.
+	Directory string `mapstructure:"directory"`
+	// Output is the directory of the unpacked files, relative to the module or system directory.
+	Output string `mapstructure:"output"`
+	// Html is whether the rich-text fields are written into their own files.
+	Html bool `mapstructure:"html"`
+	// Markdown is whether the rich-text fields are written into their own files, converted to Markdown.
+	Markdown bool `mapstructure:"markdown"`
+	// HtmlFields are the paths of the rich-text fields, by document name.
+	HtmlFields map[string][]string `mapstructure:"htmlFields"`
+	// Sparse is whether the fields having their default value are left out of the unpacked files.
+	Sparse bool `mapstructure:"sparse"`
+	// Canonical is whether the unpacked files are written in a canonical form, giving stable diffs.
+	Canonical bool `mapstructure:"canonical"`
+	// Packs are the settings overridden per pack, by pack name.
+	Packs map[string]PackConfig `mapstructure:"packs"`
+
+	// forced are the settings given by a flag or an environment variable, which prevail over the per pack settings.
+	forced map[string]bool
+}
+
+// Find looks for the project configuration file in the given directory then in its parents, up to the root of the
+// project: the first directory holding a module.json or system.json manifest.
+func Find(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		file := filepath.Join(dir, FileName)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file, true
+		}
+		if manifest.Exists(dir) {
+			return "", false
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Load builds the configuration from viper, to which the flags of the running command must be bound.
+func Load(flags *pflag.FlagSet) (*Config, error) {
+	c := &Config{forced: map[string]bool{}}
+	if err := viper.UnmarshalKey("packs", &c.Packs); err != nil {
+		return nil, err
+	}
+
+	c.Directory = viper.GetString("directory")
+	c.Format = viper.GetString("format")
+	c.Output = viper.GetString("output")
+	c.Filename = viper.GetString("filename")
+	c.Layout = viper.GetString("layout")
+	c.Canonical = viper.GetBool("canonical")
+	c.Sparse = viper.GetBool("sparse")
+	c.Html = viper.GetBool("html")
+	c.Markdown = viper.GetBool("markdown")
+	c.HtmlFields = viper.GetStringMapStringSlice("htmlFields")
+	c.Exclude = viper.GetStringSlice("exclude")
+
+	for _, key := range []string{"directory", "filename", "format", "layout", "output"} {
+		if f := flags.Lookup(key); f != nil && f.Changed {
+			c.forced[key] = true
+		}
+		if _, ok := os.LookupEnv(EnvName(key)); ok {
+			c.forced[key] = true
+		}
+	}
+
+	return c, nil
+}
+
+// EnvName returns the name of the environment variable overriding the given setting.
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// Force overrides a setting for every pack, as done by a flag.
+func (c *Config) Force(key string, value string) {
+	switch key {
+	case "format":
+		c.Format = value
+	case "directory":
+		c.Directory = value
+	}
+	c.forced[key] = true
+}
+
+// Pack returns the settings of the given pack.
+func (c *Config) Pack(name string) PackConfig {
+	p := c.PackConfig
+	o, ok := c.Packs[name]
+	if !ok {
+		return p
+	}
+
+	if o.Format != "" && !c.forced["format"] {
+		p.Format = o.Format
+	}
+	if o.Filename != "" && !c.forced["filename"] {
+		p.Filename = o.Filename
+	}
+	if o.Layout != "" && !c.forced["layout"] {
+		p.Layout = o.Layout
+	}
+	if o.Sources != "" {
+		p.Sources = o.Sources
+	}
+	if o.Exclude != nil {
+		p.Exclude = o.Exclude
+	}
+
+	return p
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPackExclude(t *testing.T) {
+	c := &Config{
+		PackConfig: PackConfig{Exclude: []string{"_stats.modifiedTime", "ownership.{id}"}},
+		Packs: map[string]PackConfig{
+			"macros": {Exclude: []string{"_stats.coreVersion"}},
+			"items":  {Exclude: []string{}},
+			"actors": {Format: "yaml"},
+		},
+		forced: map[string]bool{},
+	}
+
+	tests := []struct {
+		pack     string
+		expected []string
+	}{
+		{"macros", []string{"_stats.coreVersion"}},
+		{"items", []string{}},
+		{"actors", []string{"_stats.modifiedTime", "ownership.{id}"}},
+		{"scenes", []string{"_stats.modifiedTime", "ownership.{id}"}},
+	}
+	for _, tt := range tests {
+		if got := c.Pack(tt.pack).Exclude; !slices.Equal(got, tt.expected) {
+			t.Errorf("Pack(%s).Exclude = %v, expected %v", tt.pack, got, tt.expected)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"home/mods/mod/packs/items", "home/mods/bare/sub", "home/mods/own/sub"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"home/" + FileName, "home/mods/mod/module.json", "home/mods/own/system.json", "home/mods/own/" + FileName} {
+		if err := os.WriteFile(filepath.Join(root, file), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		dir      string
+		expected string
+	}{
+		{"home/mods/mod", ""},
+		{"home/mods/mod/packs/items", ""},
+		{"home/mods/own", "home/mods/own/" + FileName},
+		{"home/mods/own/sub", "home/mods/own/" + FileName},
+		{"home/mods/bare/sub", "home/" + FileName},
+		{"home", "home/" + FileName},
+	}
+	for _, tt := range tests {
+		file, ok := Find(filepath.Join(root, tt.dir))
+		expected := ""
+		if tt.expected != "" {
+			expected = filepath.Join(root, tt.expected)
+		}
+		if file != expected || ok != (expected != "") {
+			t.Errorf("Find(%s) = %s, %t, expected %s", tt.dir, file, ok, expected)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around the changes.
+const context = 3
+
+// Unified returns the differences between two texts, line by line, in the unified format, with the given names in its
+// header. It returns an empty string if the texts are the same.
+func Unified(nameA string, nameB string, a string, b string) string {
+	if a == b {
+		return ""
+	}
+
+	linesA := splitLines(a)
+	linesB := splitLines(b)
+	ops := compare(linesA, linesB)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	for start := 0; start < len(ops); {
+		// Find the next change, then the end of the hunk: a change followed by more than twice the context.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		end := start
+		for unchanged := 0; end < len(ops) && unchanged <= 2*context; end++ {
+			if ops[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		for end > start && ops[end-1].kind == ' ' {
+			end--
+		}
+
+		from := max(start-context, 0)
+		to := min(end+context, len(ops))
+		hunk := ops[from:to]
+		countA, countB := 0, 0
+		for _, op := range hunk {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", hunk[0].lineA+1, countA, hunk[0].lineB+1, countB)
+		for _, op := range hunk {
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.text)
+		}
+
+		start = to
+	}
+
+	return out.String()
+}
+
+// operation is a line of the differences: ' ' for an unchanged line, '-' for a removed one and '+' for an added one.
+// lineA and lineB are the numbers of the lines preceding it in both texts, from 0.
+type operation struct {
+	kind  byte
+	text  string
+	lineA int
+	lineB int
+}
+
+// compare returns the operations turning a into b, from their longest common subsequence of lines.
+func compare(a []string, b []string) []operation {
+	// The common prefix and suffix are skipped, as documents only change in a few places.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of midA[i:] and midB[j:].
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []operation
+	i, j := 0, 0
+	emit := func(kind byte, text string) {
+		ops = append(ops, operation{kind: kind, text: text, lineA: i, lineB: j})
+	}
+	for ; i < prefix; i, j = i+1, j+1 {
+		emit(' ', a[i])
+	}
+	for mi, mj := 0, 0; mi < len(midA) || mj < len(midB); {
+		switch {
+		case mi < len(midA) && mj < len(midB) && midA[mi] == midB[mj]:
+			emit(' ', midA[mi])
+			mi, mj, i, j = mi+1, mj+1, i+1, j+1
+		case mj == len(midB) || mi < len(midA) && lcs[mi+1][mj] >= lcs[mi][mj+1]:
+			emit('-', midA[mi])
+			mi, i = mi+1, i+1
+		default:
+			emit('+', midB[mj])
+			mj, j = mj+1, j+1
+		}
+	}
+	for ; i < len(a); i, j = i+1, j+1 {
+		emit(' ', a[i])
+	}
+
+	return ops
+}
+
+func splitLines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
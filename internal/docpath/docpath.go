@@ -0,0 +1,197 @@
+package docpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MapStrings walks the decoded JSON value v and replaces every string it contains by the value returned by fn.
+// Objects are visited in key order so that callers reporting findings get a stable output.
+func MapStrings(v interface{}, fn func(path string, s string) string) interface{} {
+	return mapStrings("", v, fn)
+}
+
+func mapStrings(path string, v interface{}, fn func(path string, s string) string) interface{} {
+	switch val := v.(type) {
+	case string:
+		return fn(path, val)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			val[k] = mapStrings(join(path, k), val[k], fn)
+		}
+	case []interface{}:
+		for i := range val {
+			val[i] = mapStrings(join(path, strconv.Itoa(i)), val[i], fn)
+		}
+	}
+
+	return v
+}
+
+func join(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// Decode decodes a raw JSON document, keeping numbers as they are written.
+func Decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Encode encodes a document decoded by Decode back to compact JSON, without escaping HTML.
+func Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// Get returns the value found at the dotted path of the decoded JSON value v, array elements being given by their
+// index, e.g. "system.damage.parts.0".
+func Get(v interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return v, true
+	}
+
+	for _, s := range strings.Split(path, ".") {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = val[s]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(s)
+			if err != nil || i < 0 || i >= len(val) {
+				return nil, false
+			}
+			v = val[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// Set sets the value found at the dotted path of the object obj, creating the missing objects on the way. Array
+// elements are given by their index, e.g. "system.damage.parts.0", the index following the last element appending one.
+// It reports whether it succeeded, which is not the case when the path goes through a value which is neither an object
+// nor an array, or through an index out of the array.
+func Set(obj map[string]interface{}, path string, value interface{}) bool {
+	_, ok := set(obj, strings.Split(path, "."), value)
+
+	return ok
+}
+
+// set sets the value at the path inside v, and returns v, which is a new slice when an element is appended to it.
+func set(v interface{}, segments []string, value interface{}) (interface{}, bool) {
+	if len(segments) == 0 {
+		return value, true
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		child, exists := val[segments[0]]
+		if (!exists || child == nil) && len(segments) > 1 {
+			child = map[string]interface{}{}
+		}
+		child, ok := set(child, segments[1:], value)
+		if ok {
+			val[segments[0]] = child
+		}
+		return val, ok
+	case []interface{}:
+		i, err := strconv.Atoi(segments[0])
+		if err != nil || i < 0 || i > len(val) {
+			return val, false
+		}
+		var child interface{}
+		if i < len(val) {
+			child = val[i]
+		} else if len(segments) > 1 {
+			child = map[string]interface{}{}
+		}
+		child, ok := set(child, segments[1:], value)
+		if !ok {
+			return val, false
+		}
+		if i == len(val) {
+			return append(val, child), true
+		}
+		val[i] = child
+		return val, true
+	}
+
+	return v, false
+}
+
+// Native replaces the numbers of a document decoded by Decode by integers or floats, which the YAML and TOML encoders
+// would otherwise write as strings.
+func Native(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		for k := range val {
+			val[k] = Native(val[k])
+		}
+	case []interface{}:
+		for i := range val {
+			val[i] = Native(val[i])
+		}
+	}
+
+	return v
+}
+
+// EachObject calls fn for every object found in the decoded JSON value v, including v itself, parents first.
+func EachObject(v interface{}, fn func(path string, obj map[string]interface{})) {
+	eachObject("", v, fn)
+}
+
+func eachObject(path string, v interface{}, fn func(path string, obj map[string]interface{})) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		fn(path, val)
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			eachObject(join(path, k), val[k], fn)
+		}
+	case []interface{}:
+		for i := range val {
+			eachObject(join(path, strconv.Itoa(i)), val[i], fn)
+		}
+	}
+}
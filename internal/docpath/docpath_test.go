@@ -0,0 +1,63 @@
+package docpath
+
+import (
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		doc      string
+		path     string
+		value    interface{}
+		expected string
+		ok       bool
+	}{
+		{`{}`, "name", "Dagger", `{"name":"Dagger"}`, true},
+		{`{}`, "system.price.value", "2", `{"system":{"price":{"value":"2"}}}`, true},
+		{`{"system":null}`, "system.weight", "1", `{"system":{"weight":"1"}}`, true},
+		{`{"system":{"damage":{"parts":[["1d4","piercing"]]}}}`, "system.damage.parts.0.0", "1d6",
+			`{"system":{"damage":{"parts":[["1d6","piercing"]]}}}`, true},
+		{`{"tags":["a"]}`, "tags.1", "b", `{"tags":["a","b"]}`, true},
+		{`{"effects":[]}`, "effects.0.name", "Bless", `{"effects":[{"name":"Bless"}]}`, true},
+		{`{"tags":["a"]}`, "tags.2", "c", `{"tags":["a"]}`, false},
+		{`{"tags":["a"]}`, "tags.x", "c", `{"tags":["a"]}`, false},
+		{`{"name":"Dagger"}`, "name.first", "x", `{"name":"Dagger"}`, false},
+	}
+
+	for _, tt := range tests {
+		v, err := Decode([]byte(tt.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok := Set(v.(map[string]interface{}), tt.path, tt.value)
+		if ok != tt.ok {
+			t.Errorf("Set(%s, %s) reported %v, expected %v", tt.doc, tt.path, ok, tt.ok)
+		}
+		got, _ := Encode(v)
+		if string(got) != tt.expected {
+			t.Errorf("Set(%s, %s) gave %s, expected %s", tt.doc, tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	v, _ := Decode([]byte(`{"system":{"damage":{"parts":[["1d4","piercing"]]}},"folder":null}`))
+	tests := []struct {
+		path     string
+		expected interface{}
+		ok       bool
+	}{
+		{"system.damage.parts.0.1", "piercing", true},
+		{"folder", nil, true},
+		{"system.damage.parts.1", nil, false},
+		{"system.damage.parts.-1", nil, false},
+		{"system.missing", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Get(v, tt.path)
+		if ok != tt.ok || got != tt.expected {
+			t.Errorf("Get(%s) = %v, %v, expected %v, %v", tt.path, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package documents
 
 import (
 	"encoding/json"
-	"fmt"
 	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
 )
 
@@ -12,9 +11,10 @@ type ActorDocument struct {
 	Type           string                  `json:"type" yaml:"type"`
 	System         *System                 `json:"system" yaml:"system"`
 	PrototypeToken *PrototypeTokenDocument `json:"prototypeToken" yaml:"prototypeToken"`
-	Items          []*Document             `json:"-" yaml:"-"`
+	Items          []*Document             `json:"items" yaml:"items"`
 	ItemsIds       []string                `json:"-" yaml:"-"`
-	Effects        *[]ActiveEffectDocument `json:"effects" yaml:"effects"`
+	Effects        []*Document             `json:"effects" yaml:"effects"`
+	EffectsIds     []string                `json:"-" yaml:"-"`
 	Folder         string                  `json:"folder" yaml:"folder"`
 	Sort           int                     `json:"sort" yaml:"sort"`
 	Ownership      *Ownership              `json:"ownership" yaml:"ownership"`
@@ -22,43 +22,30 @@ type ActorDocument struct {
 	Stats          *DocumentStats          `json:"_stats" yaml:"_stats"`
 }
 
+// UnmarshalJSON decodes an actor as stored in the LevelDB, where embedded documents are referenced by their ids.
 func (a *ActorDocument) UnmarshalJSON(data []byte) error {
-	var aux struct {
-		Items []string `json:"items"`
-	}
+	type actor ActorDocument
+	aux := struct {
+		*actor
+		Items   []string `json:"items"`
+		Effects []string `json:"effects"`
+	}{actor: (*actor)(a)}
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
 	a.ItemsIds = aux.Items
+	a.EffectsIds = aux.Effects
 
 	return nil
 }
 
-func (a *ActorDocument) MarshalJSON() ([]byte, error) {
-	var aux struct {
-		Items []string `json:"items"`
-	}
-	aux.Items = a.ItemsIds
-	return json.Marshal(aux)
-}
-
 func (a *ActorDocument) HydrateCollections(fvttdb *fvttdb.FvttDb) error {
-	fmt.Println(a.Name, a.ItemsIds)
-
-	for _, id := range a.ItemsIds {
-		key := "!actors.items!" + a.Id + "." + id
-		v, err := fvttdb.Get(key)
-		if err != nil {
-			return fmt.Errorf("cannot get doc %s: %s\n", id, err)
-		}
-
-		doc, err := Create("", "items", v)
-		if err != nil {
-			return fmt.Errorf("cannot create doc %s: %s\n", id, err)
-		}
-
-		//fmt.Println(doc)
-		a.Items = append(a.Items, doc)
+	var err error
+	if a.Items, err = hydrateEmbedded(fvttdb, &a.baseDocument, "items", a.ItemsIds); err != nil {
+		return err
+	}
+	if a.Effects, err = hydrateEmbedded(fvttdb, &a.baseDocument, "effects", a.EffectsIds); err != nil {
+		return err
 	}
 
 	return nil
@@ -9,7 +9,7 @@ type sightData struct {
 	Range       float64 `json:"range" yaml:"range"`
 	Angle       float64 `json:"angle" yaml:"angle"`
 	VisionMode  string  `json:"visionMode" yaml:"visionMode"`
-	Color       string  `json:"color" yaml:"color"`
+	Color       *string `json:"color" yaml:"color"`
 	Attenuation float64 `json:"attenuation" yaml:"attenuation"`
 	Brightness  float64 `json:"brightness" yaml:"brightness"`
 	Saturation  float64 `json:"saturation" yaml:"saturation"`
@@ -27,13 +27,13 @@ type occludableData struct {
 }
 
 type ringColorData struct {
-	Ring       string `json:"ring" yaml:"ring"`
-	Background string `json:"background" yaml:"background"`
+	Ring       *string `json:"ring" yaml:"ring"`
+	Background *string `json:"background" yaml:"background"`
 }
 
 type ringSubjectData struct {
 	Scale   float64 `json:"scale" yaml:"scale"`
-	Texture string  `json:"texture" yaml:"texture"`
+	Texture *string `json:"texture" yaml:"texture"`
 }
 
 type ringData struct {
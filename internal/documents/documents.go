@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
-	"regexp"
+	"github.com/djlechuck/fvtt-packs/internal/slug"
+	"path"
+	"reflect"
+	"strings"
 )
 
 type Flags map[string]interface{}
@@ -38,6 +41,9 @@ type TextureData struct {
 	AlphaThreshold float64 `json:"alphaThreshold" yaml:"alphaThreshold"`
 }
 
+// DefaultFilename is the default template of the file names of the unpacked documents.
+const DefaultFilename = "{name}_{id}"
+
 type baseDocument struct {
 	Pack string `json:"-" yaml:"-"`
 	Key  string `json:"_key" yaml:"_key"`
@@ -48,20 +54,105 @@ type baseDocument struct {
 type Document interface {
 	SetPack(pack string)
 	SetKey(collection string)
-	ExportName(isYaml bool) string
 	HydrateCollections(fvttdb *fvttdb.FvttDb) error
+	GetId() string
+	base() *baseDocument
 }
 
 var documentTypeMapping = map[string]func() Document{
 	"actors":  func() Document { return &ActorDocument{} },
+	"effects": func() Document { return &ActiveEffectDocument{} },
 	"folders": func() Document { return &FolderDocument{} },
 	"items":   func() Document { return &ItemDocument{} },
+	"journal": func() Document { return &JournalEntryDocument{} },
+	"pages":   func() Document { return &JournalEntryPageDocument{} },
+	"results": func() Document { return &TableResultDocument{} },
+	"tables":  func() Document { return &RollTableDocument{} },
+	"scenes":  func() Document { return &SceneDocument{} },
+	"tokens":  func() Document { return &TokenDocument{} },
+	// The documents having no structure of their own, e.g. the macros or the placeables of the scenes, are kept as
+	// they are.
+	"adventures": func() Document { return &GenericDocument{} },
+	"cards":      func() Document { return &GenericDocument{} },
+	"macros":     func() Document { return &GenericDocument{} },
+	"playlists":  func() Document { return &GenericDocument{} },
+	"drawings":   func() Document { return &GenericDocument{} },
+	"lights":     func() Document { return &GenericDocument{} },
+	"notes":      func() Document { return &GenericDocument{} },
+	"sounds":     func() Document { return &GenericDocument{} },
+	"regions":    func() Document { return &GenericDocument{} },
+	"behaviors":  func() Document { return &GenericDocument{} },
+	"templates":  func() Document { return &GenericDocument{} },
+	"tiles":      func() Document { return &GenericDocument{} },
+	"walls":      func() Document { return &GenericDocument{} },
+}
+
+// collectionNames maps each Foundry document name to the collection used in the LevelDB keys.
+var collectionNames = map[string]string{
+	"Actor":            "actors",
+	"Adventure":        "adventures",
+	"Cards":            "cards",
+	"Folder":           "folders",
+	"Item":             "items",
+	"JournalEntry":     "journal",
+	"Macro":            "macros",
+	"Playlist":         "playlists",
+	"RollTable":        "tables",
+	"Scene":            "scenes",
+	"ActiveEffect":     "effects",
+	"JournalEntryPage": "pages",
+	"TableResult":      "results",
+	"Token":            "tokens",
+	"Drawing":          "drawings",
+	"AmbientLight":     "lights",
+	"Note":             "notes",
+	"AmbientSound":     "sounds",
+	"Region":           "regions",
+	"RegionBehavior":   "behaviors",
+	"MeasuredTemplate": "templates",
+	"Tile":             "tiles",
+	"Wall":             "walls",
+}
+
+// packTypes are the document names a compendium pack can contain.
+var packTypes = []string{"Actor", "Adventure", "Cards", "Item", "JournalEntry", "Macro", "Playlist", "RollTable", "Scene"}
+
+// IsPackType reports whether a compendium pack can contain documents of the given name.
+func IsPackType(documentName string) bool {
+	for _, t := range packTypes {
+		if t == documentName {
+			return true
+		}
+	}
+
+	return false
 }
 
-func (b *baseDocument) safeFilename() string {
-	reg := regexp.MustCompile(`[^a-zA-Z0-9А-я]`)
+// CollectionName returns the LevelDB collection of the given document name, e.g. "items" for "Item".
+func CollectionName(documentName string) (string, bool) {
+	c, ok := collectionNames[documentName]
+
+	return c, ok
+}
+
+// DocumentName returns the document name of the given LevelDB collection, e.g. "Item" for "items" or "actors.items".
+func DocumentName(collection string) (string, bool) {
+	collection = collection[strings.LastIndex(collection, ".")+1:]
+	for name, c := range collectionNames {
+		if c == collection {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+func (b *baseDocument) base() *baseDocument {
+	return b
+}
 
-	return reg.ReplaceAllString(b.Name, "_")
+func (b *baseDocument) GetId() string {
+	return b.Id
 }
 
 func (b *baseDocument) SetPack(pack string) {
@@ -72,17 +163,51 @@ func (b *baseDocument) SetKey(collection string) {
 	b.Key = "!" + collection + "!" + b.Id
 }
 
-func (b *baseDocument) ExportName(isYaml bool) string {
-	extension := "json"
-	if isYaml {
-		extension = "yml"
+// FolderOf returns the id of the folder containing the document, or an empty string if it is not in a folder.
+func FolderOf(doc Document) string {
+	return stringField(doc, "Folder")
+}
+
+// stringField returns the value of a string field the document type may have, or an empty string.
+func stringField(doc Document, name string) string {
+	if f := reflect.ValueOf(doc).Elem().FieldByName(name); f.IsValid() && f.Kind() == reflect.String {
+		return f.String()
 	}
 
-	if b.Name != "" {
-		return b.safeFilename() + "_" + b.Id + "." + extension
+	return ""
+}
+
+// ExportName returns the file name of the document, with the given extension, relative to the sources directory of its
+// pack. It is built from a template, where {name}, {id} and {type} are replaced by the slugs of the name, id and type of
+// the document. Slashes of the template create subdirectories. An empty value is replaced by the id.
+func ExportName(doc Document, template string, extension string) string {
+	b := doc.base()
+
+	return FileName(b.Name, b.Id, stringField(doc, "Type"), template, extension)
+}
+
+// FileName returns the file name of a document given by its name, id and type, as ExportName does.
+func FileName(name string, id string, docType string, template string, extension string) string {
+	placeholders := map[string]string{"{name}": name, "{id}": id, "{type}": docType}
+	var parts []string
+	for _, part := range strings.Split(template, "/") {
+		for placeholder, value := range placeholders {
+			if !strings.Contains(part, placeholder) {
+				continue
+			}
+			value = slug.Make(value)
+			if value == "" {
+				value = id
+			}
+			part = strings.ReplaceAll(part, placeholder, value)
+		}
+		if slug.IsWindowsReserved(part) {
+			part += "_"
+		}
+		parts = append(parts, part)
 	}
 
-	return b.Key + "." + extension
+	return path.Join(parts...) + extension
 }
 
 func Create(pack string, docType string, v []byte) (*Document, error) {
@@ -100,3 +225,35 @@ func Create(pack string, docType string, v []byte) (*Document, error) {
 
 	return &doc, nil
 }
+
+// hydrateEmbedded returns the documents of an embedded collection of the parent, e.g. the "items" of an actor, stored
+// under keys like "!actors.items!actorId.itemId".
+func hydrateEmbedded(fvttdb *fvttdb.FvttDb, parent *baseDocument, collection string, ids []string) ([]*Document, error) {
+	parts := strings.Split(parent.Key, "!")
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("invalid key %s\n", parent.Key)
+	}
+
+	docs := []*Document{}
+	for _, id := range ids {
+		key := "!" + parts[1] + "." + collection + "!" + parts[2] + "." + id
+		v, err := fvttdb.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get doc %s: %s\n", id, err)
+		}
+
+		doc, err := Create(parent.Pack, collection, v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create doc %s: %s\n", id, err)
+		}
+		(*doc).base().Key = key
+
+		if err := (*doc).HydrateCollections(fvttdb); err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
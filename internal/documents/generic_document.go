@@ -0,0 +1,91 @@
+package documents
+
+import (
+	"encoding/json"
+	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
+	"strings"
+)
+
+// genericCollections are the collections embedded inside the documents read as GenericDocument, by collection.
+var genericCollections = map[string][]string{
+	"cards":          {"cards"},
+	"playlists":      {"sounds"},
+	"scenes.regions": {"behaviors"},
+}
+
+// GenericDocument is a document having no structure of its own, e.g. a macro or a wall. Its fields are kept as they
+// are stored in the LevelDB, but its embedded documents, e.g. the sounds of a playlist.
+type GenericDocument struct {
+	baseDocument `yaml:"-"`
+	Fields       map[string]interface{} `json:"-" yaml:"-"`
+	// Type and Folder are copies of the fields of the same name, used to name and place the file of the document.
+	Type   string `json:"-" yaml:"-"`
+	Folder string `json:"-" yaml:"-"`
+}
+
+func (d *GenericDocument) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &d.Fields); err != nil {
+		return err
+	}
+	d.Id, _ = d.Fields["_id"].(string)
+	d.Name, _ = d.Fields["name"].(string)
+	d.Type, _ = d.Fields["type"].(string)
+	d.Folder, _ = d.Fields["folder"].(string)
+	delete(d.Fields, "_id")
+	delete(d.Fields, "_key")
+
+	return nil
+}
+
+func (d *GenericDocument) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.fields())
+}
+
+func (d *GenericDocument) MarshalYAML() (interface{}, error) {
+	return d.fields(), nil
+}
+
+// fields returns the fields of the document, with its _key and _id.
+func (d *GenericDocument) fields() map[string]interface{} {
+	m := make(map[string]interface{}, len(d.Fields)+2)
+	for k, v := range d.Fields {
+		m[k] = v
+	}
+	m["_key"] = d.Key
+	m["_id"] = d.Id
+
+	return m
+}
+
+func (d *GenericDocument) HydrateCollections(fvttdb *fvttdb.FvttDb) error {
+	for _, collection := range genericCollections[keyCollection(d.Key)] {
+		list, ok := d.Fields[collection].([]interface{})
+		if !ok {
+			continue
+		}
+		var ids []string
+		for _, id := range list {
+			if s, ok := id.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+
+		docs, err := hydrateEmbedded(fvttdb, &d.baseDocument, collection, ids)
+		if err != nil {
+			return err
+		}
+		d.Fields[collection] = docs
+	}
+
+	return nil
+}
+
+// keyCollection returns the collection of a LevelDB key, e.g. "scenes.regions" for "!scenes.regions!a.b".
+func keyCollection(key string) string {
+	parts := strings.Split(key, "!")
+	if len(parts) < 3 {
+		return ""
+	}
+
+	return parts[1]
+}
@@ -1,22 +1,42 @@
 package documents
 
 import (
+	"encoding/json"
 	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
 )
 
 type ItemDocument struct {
 	baseDocument `yaml:",inline"`
-	Type         string                  `json:"type" yaml:"type"`
-	Img          string                  `json:"img" yaml:"img"`
-	System       *System                 `json:"system" yaml:"system"`
-	Effects      *[]ActiveEffectDocument `json:"effects" yaml:"effects"`
-	Folder       string                  `json:"folder" yaml:"folder"`
-	Sort         int                     `json:"sort" yaml:"sort"`
-	Ownership    *Ownership              `json:"ownership" yaml:"ownership"`
-	Flags        *Flags                  `json:"flags" yaml:"flags"`
-	Stats        *DocumentStats          `json:"_stats" yaml:"_stats"`
+	Type         string         `json:"type" yaml:"type"`
+	Img          string         `json:"img" yaml:"img"`
+	System       *System        `json:"system" yaml:"system"`
+	Effects      []*Document    `json:"effects" yaml:"effects"`
+	EffectsIds   []string       `json:"-" yaml:"-"`
+	Folder       string         `json:"folder" yaml:"folder"`
+	Sort         int            `json:"sort" yaml:"sort"`
+	Ownership    *Ownership     `json:"ownership" yaml:"ownership"`
+	Flags        *Flags         `json:"flags" yaml:"flags"`
+	Stats        *DocumentStats `json:"_stats" yaml:"_stats"`
 }
 
-func (d *ItemDocument) HydrateCollections(fvttdb *fvttdb.FvttDb) error {
+// UnmarshalJSON decodes an item as stored in the LevelDB, where embedded documents are referenced by their ids.
+func (d *ItemDocument) UnmarshalJSON(data []byte) error {
+	type item ItemDocument
+	aux := struct {
+		*item
+		Effects []string `json:"effects"`
+	}{item: (*item)(d)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	d.EffectsIds = aux.Effects
+
 	return nil
 }
+
+func (d *ItemDocument) HydrateCollections(fvttdb *fvttdb.FvttDb) error {
+	var err error
+	d.Effects, err = hydrateEmbedded(fvttdb, &d.baseDocument, "effects", d.EffectsIds)
+
+	return err
+}
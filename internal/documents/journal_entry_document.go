@@ -0,0 +1,39 @@
+package documents
+
+import (
+	"encoding/json"
+	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
+)
+
+type JournalEntryDocument struct {
+	baseDocument `yaml:",inline"`
+	Pages        []*Document    `json:"pages" yaml:"pages"`
+	PagesIds     []string       `json:"-" yaml:"-"`
+	Folder       string         `json:"folder" yaml:"folder"`
+	Sort         int            `json:"sort" yaml:"sort"`
+	Ownership    *Ownership     `json:"ownership" yaml:"ownership"`
+	Flags        *Flags         `json:"flags" yaml:"flags"`
+	Stats        *DocumentStats `json:"_stats" yaml:"_stats"`
+}
+
+// UnmarshalJSON decodes a journal entry as stored in the LevelDB, where pages are referenced by their ids.
+func (d *JournalEntryDocument) UnmarshalJSON(data []byte) error {
+	type journal JournalEntryDocument
+	aux := struct {
+		*journal
+		Pages []string `json:"pages"`
+	}{journal: (*journal)(d)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	d.PagesIds = aux.Pages
+
+	return nil
+}
+
+func (d *JournalEntryDocument) HydrateCollections(fvttdb *fvttdb.FvttDb) error {
+	var err error
+	d.Pages, err = hydrateEmbedded(fvttdb, &d.baseDocument, "pages", d.PagesIds)
+
+	return err
+}
@@ -0,0 +1,31 @@
+package documents
+
+import "github.com/djlechuck/fvtt-packs/internal/fvttdb"
+
+type JournalEntryPageDocument struct {
+	baseDocument `yaml:",inline"`
+	Type         string `json:"type" yaml:"type"`
+	Title        struct {
+		Show  bool `json:"show" yaml:"show"`
+		Level int  `json:"level" yaml:"level"`
+	} `json:"title" yaml:"title"`
+	Image struct {
+		Caption string `json:"caption" yaml:"caption"`
+	} `json:"image" yaml:"image"`
+	Text struct {
+		Content  string `json:"content" yaml:"content"`
+		Format   int    `json:"format" yaml:"format"`
+		Markdown string `json:"markdown" yaml:"markdown"`
+	} `json:"text" yaml:"text"`
+	Video     map[string]interface{} `json:"video" yaml:"video"`
+	Src       string                 `json:"src" yaml:"src"`
+	System    *System                `json:"system" yaml:"system"`
+	Sort      int                    `json:"sort" yaml:"sort"`
+	Ownership *Ownership             `json:"ownership" yaml:"ownership"`
+	Flags     *Flags                 `json:"flags" yaml:"flags"`
+	Stats     *DocumentStats         `json:"_stats" yaml:"_stats"`
+}
+
+func (d *JournalEntryPageDocument) HydrateCollections(fvttdb *fvttdb.FvttDb) error {
+	return nil
+}
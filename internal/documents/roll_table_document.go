@@ -0,0 +1,44 @@
+package documents
+
+import (
+	"encoding/json"
+	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
+)
+
+type RollTableDocument struct {
+	baseDocument `yaml:",inline"`
+	Img          string         `json:"img" yaml:"img"`
+	Description  string         `json:"description" yaml:"description"`
+	Results      []*Document    `json:"results" yaml:"results"`
+	ResultsIds   []string       `json:"-" yaml:"-"`
+	Formula      string         `json:"formula" yaml:"formula"`
+	Replacement  bool           `json:"replacement" yaml:"replacement"`
+	DisplayRoll  bool           `json:"displayRoll" yaml:"displayRoll"`
+	Folder       string         `json:"folder" yaml:"folder"`
+	Sort         int            `json:"sort" yaml:"sort"`
+	Ownership    *Ownership     `json:"ownership" yaml:"ownership"`
+	Flags        *Flags         `json:"flags" yaml:"flags"`
+	Stats        *DocumentStats `json:"_stats" yaml:"_stats"`
+}
+
+// UnmarshalJSON decodes a roll table as stored in the LevelDB, where results are referenced by their ids.
+func (d *RollTableDocument) UnmarshalJSON(data []byte) error {
+	type table RollTableDocument
+	aux := struct {
+		*table
+		Results []string `json:"results"`
+	}{table: (*table)(d)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	d.ResultsIds = aux.Results
+
+	return nil
+}
+
+func (d *RollTableDocument) HydrateCollections(fvttdb *fvttdb.FvttDb) error {
+	var err error
+	d.Results, err = hydrateEmbedded(fvttdb, &d.baseDocument, "results", d.ResultsIds)
+
+	return err
+}
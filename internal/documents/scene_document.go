@@ -1,5 +1,12 @@
 package documents
 
+import (
+	"encoding/json"
+	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
+)
+
+// SceneDocument is a scene as of Foundry 12. Its placeables are embedded documents: tokens, and the drawings, lights,
+// notes, sounds, regions, templates, tiles and walls read as GenericDocument.
 type SceneDocument struct {
 	baseDocument        `yaml:",inline"`
 	Active              bool         `json:"active" yaml:"active"`
@@ -7,19 +14,15 @@ type SceneDocument struct {
 	NavOrder            int          `json:"navOrder" yaml:"navOrder"`
 	NavName             string       `json:"navName" yaml:"navName"`
 	Background          *TextureData `json:"background" yaml:"background"`
-	Foreground          string       `json:"foreground" yaml:"foreground"`
-	ForegroundElevation int          `json:"foregroundElevation" yaml:"foregroundElevation"`
-	Thumb               string       `json:"thumb" yaml:"thumb"`
+	Foreground          *string      `json:"foreground" yaml:"foreground"`
+	ForegroundElevation *float64     `json:"foregroundElevation" yaml:"foregroundElevation"`
+	Thumb               *string      `json:"thumb" yaml:"thumb"`
 	Width               int          `json:"width" yaml:"width"`
 	Height              int          `json:"height" yaml:"height"`
-	Padding             int          `json:"padding" yaml:"padding"`
-	Initial             struct {
-		X     int     `json:"x" yaml:"x"`
-		Y     int     `json:"y" yaml:"y"`
-		Scale float64 `json:"scale" yaml:"scale"`
-	} `json:"initial" yaml:"initial"`
-	BackgroundColor string `json:"backgroundColor" yaml:"backgroundColor"`
-	Grid            struct {
+	Padding             float64      `json:"padding" yaml:"padding"`
+	Initial             interface{}  `json:"initial" yaml:"initial"`
+	BackgroundColor     string       `json:"backgroundColor" yaml:"backgroundColor"`
+	Grid                struct {
 		Type      int     `json:"type" yaml:"type"`
 		Size      int     `json:"size" yaml:"size"`
 		Style     string  `json:"style" yaml:"style"`
@@ -29,31 +32,98 @@ type SceneDocument struct {
 		Distance  float64 `json:"distance" yaml:"distance"`
 		Units     string  `json:"units" yaml:"units"`
 	} `json:"grid" yaml:"grid"`
-	TokenVision          bool           `json:"tokenVision" yaml:"tokenVision"`
-	FogExploration       bool           `json:"fogExploration" yaml:"fogExploration"`
-	FogReset             int            `json:"fogReset" yaml:"fogReset"`
-	GlobalLight          bool           `json:"globalLight" yaml:"globalLight"`
-	GlobalLightThreshold float64        `json:"globalLightThreshold" yaml:"globalLightThreshold"`
-	Darkness             float64        `json:"darkness" yaml:"darkness"`
-	FogOverlay           string         `json:"fogOverlay" yaml:"fogOverlay"`
-	FogExploredColor     string         `json:"fogExploredColor" yaml:"fogExploredColor"`
-	FogUnexploredColor   string         `json:"fogUnexploredColor" yaml:"fogUnexploredColor"`
-	Drawings             []interface{}  `json:"drawings" yaml:"drawings"`
-	Tokens               []interface{}  `json:"tokens" yaml:"tokens"`
-	Lights               []interface{}  `json:"lights" yaml:"lights"`
-	Notes                []interface{}  `json:"notes" yaml:"notes"`
-	Sounds               []interface{}  `json:"sounds" yaml:"sounds"`
-	Templates            []interface{}  `json:"templates" yaml:"templates"`
-	Tiles                []interface{}  `json:"tiles" yaml:"tiles"`
-	Walls                []interface{}  `json:"walls" yaml:"walls"`
-	Playlist             []interface{}  `json:"playlist" yaml:"playlist"`
-	PlaylistSound        []interface{}  `json:"playlistSound" yaml:"playlistSound"`
-	Journal              []interface{}  `json:"journal" yaml:"journal"`
-	JournalEntryPage     []interface{}  `json:"journalEntryPage" yaml:"journalEntryPage"`
-	Weather              string         `json:"weather" yaml:"weather"`
-	Folder               string         `json:"folder" yaml:"folder"`
-	Sort                 int            `json:"sort" yaml:"sort"`
-	Ownership            *Ownership     `json:"ownership" yaml:"ownership"`
-	Flags                *Flags         `json:"flags" yaml:"flags"`
-	Stats                *DocumentStats `json:"_stats" yaml:"_stats"`
+	TokenVision      bool           `json:"tokenVision" yaml:"tokenVision"`
+	Fog              interface{}    `json:"fog" yaml:"fog"`
+	Environment      interface{}    `json:"environment" yaml:"environment"`
+	Drawings         []*Document    `json:"drawings" yaml:"drawings"`
+	Tokens           []*Document    `json:"tokens" yaml:"tokens"`
+	Lights           []*Document    `json:"lights" yaml:"lights"`
+	Notes            []*Document    `json:"notes" yaml:"notes"`
+	Sounds           []*Document    `json:"sounds" yaml:"sounds"`
+	Regions          []*Document    `json:"regions" yaml:"regions"`
+	Templates        []*Document    `json:"templates" yaml:"templates"`
+	Tiles            []*Document    `json:"tiles" yaml:"tiles"`
+	Walls            []*Document    `json:"walls" yaml:"walls"`
+	EmbeddedIds      sceneIds       `json:"-" yaml:"-"`
+	Playlist         *string        `json:"playlist" yaml:"playlist"`
+	PlaylistSound    *string        `json:"playlistSound" yaml:"playlistSound"`
+	Journal          *string        `json:"journal" yaml:"journal"`
+	JournalEntryPage *string        `json:"journalEntryPage" yaml:"journalEntryPage"`
+	Weather          string         `json:"weather" yaml:"weather"`
+	Folder           string         `json:"folder" yaml:"folder"`
+	Sort             int            `json:"sort" yaml:"sort"`
+	Ownership        *Ownership     `json:"ownership" yaml:"ownership"`
+	Flags            *Flags         `json:"flags" yaml:"flags"`
+	Stats            *DocumentStats `json:"_stats" yaml:"_stats"`
+}
+
+// sceneIds are the ids of the embedded documents of a scene, as stored in the LevelDB.
+type sceneIds struct {
+	Drawings  []string
+	Tokens    []string
+	Lights    []string
+	Notes     []string
+	Sounds    []string
+	Regions   []string
+	Templates []string
+	Tiles     []string
+	Walls     []string
+}
+
+// UnmarshalJSON decodes a scene as stored in the LevelDB, where embedded documents are referenced by their ids.
+func (d *SceneDocument) UnmarshalJSON(data []byte) error {
+	type scene SceneDocument
+	aux := struct {
+		*scene
+		Drawings  []string `json:"drawings"`
+		Tokens    []string `json:"tokens"`
+		Lights    []string `json:"lights"`
+		Notes     []string `json:"notes"`
+		Sounds    []string `json:"sounds"`
+		Regions   []string `json:"regions"`
+		Templates []string `json:"templates"`
+		Tiles     []string `json:"tiles"`
+		Walls     []string `json:"walls"`
+	}{scene: (*scene)(d)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	d.EmbeddedIds = sceneIds{
+		Drawings:  aux.Drawings,
+		Tokens:    aux.Tokens,
+		Lights:    aux.Lights,
+		Notes:     aux.Notes,
+		Sounds:    aux.Sounds,
+		Regions:   aux.Regions,
+		Templates: aux.Templates,
+		Tiles:     aux.Tiles,
+		Walls:     aux.Walls,
+	}
+
+	return nil
+}
+
+func (d *SceneDocument) HydrateCollections(fvttdb *fvttdb.FvttDb) error {
+	for _, c := range []struct {
+		collection string
+		docs       *[]*Document
+		ids        []string
+	}{
+		{"drawings", &d.Drawings, d.EmbeddedIds.Drawings},
+		{"tokens", &d.Tokens, d.EmbeddedIds.Tokens},
+		{"lights", &d.Lights, d.EmbeddedIds.Lights},
+		{"notes", &d.Notes, d.EmbeddedIds.Notes},
+		{"sounds", &d.Sounds, d.EmbeddedIds.Sounds},
+		{"regions", &d.Regions, d.EmbeddedIds.Regions},
+		{"templates", &d.Templates, d.EmbeddedIds.Templates},
+		{"tiles", &d.Tiles, d.EmbeddedIds.Tiles},
+		{"walls", &d.Walls, d.EmbeddedIds.Walls},
+	} {
+		var err error
+		if *c.docs, err = hydrateEmbedded(fvttdb, &d.baseDocument, c.collection, c.ids); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
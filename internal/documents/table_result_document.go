@@ -0,0 +1,22 @@
+package documents
+
+import "github.com/djlechuck/fvtt-packs/internal/fvttdb"
+
+type TableResultDocument struct {
+	baseDocument `yaml:",inline"`
+	// Type is a number before Foundry 12, and a string since.
+	Type               interface{}    `json:"type" yaml:"type"`
+	Text               string         `json:"text" yaml:"text"`
+	Img                string         `json:"img" yaml:"img"`
+	DocumentCollection string         `json:"documentCollection" yaml:"documentCollection"`
+	DocumentId         string         `json:"documentId" yaml:"documentId"`
+	Weight             int            `json:"weight" yaml:"weight"`
+	Range              []int          `json:"range" yaml:"range"`
+	Drawn              bool           `json:"drawn" yaml:"drawn"`
+	Flags              *Flags         `json:"flags" yaml:"flags"`
+	Stats              *DocumentStats `json:"_stats" yaml:"_stats"`
+}
+
+func (d *TableResultDocument) HydrateCollections(fvttdb *fvttdb.FvttDb) error {
+	return nil
+}
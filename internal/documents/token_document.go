@@ -1,12 +1,20 @@
 package documents
 
+import "github.com/djlechuck/fvtt-packs/internal/fvttdb"
+
 type TokenDocument struct {
-	baseDocument `yaml:",inline"`
-	ActorId      string      `json:"actorId" yaml:"actorId"`
-	Delta        interface{} `json:"delta" yaml:"delta"`
-	X            int         `json:"x" yaml:"x"`
-	Y            int         `json:"y" yaml:"y"`
-	Elevation    float64     `json:"elevation" yaml:"elevation"`
-	Sort         int         `json:"sort" yaml:"sort"`
-	Hidden       bool        `json:"hidden" yaml:"hidden"`
+	baseDocument      `yaml:",inline"`
+	baseTokenDocument `yaml:",inline"`
+	ActorId           *string     `json:"actorId" yaml:"actorId"`
+	Delta             interface{} `json:"delta" yaml:"delta"`
+	X                 int         `json:"x" yaml:"x"`
+	Y                 int         `json:"y" yaml:"y"`
+	Elevation         float64     `json:"elevation" yaml:"elevation"`
+	Sort              int         `json:"sort" yaml:"sort"`
+	Hidden            bool        `json:"hidden" yaml:"hidden"`
+	Regions           []string    `json:"_regions" yaml:"_regions"`
+}
+
+func (d *TokenDocument) HydrateCollections(fvttdb *fvttdb.FvttDb) error {
+	return nil
 }
@@ -0,0 +1,125 @@
+package exclude
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+// Defaults are the fields changed by Foundry on every edit, or depending on the world the document has been edited
+// in, rather than by the authors of the documents: the volatile statistics and the ownership given to the users.
+var Defaults = []string{
+	"_stats.coreVersion",
+	"_stats.lastModifiedBy",
+	"_stats.modifiedTime",
+	"_stats.systemVersion",
+	"ownership.{id}",
+}
+
+var idPattern = regexp.MustCompile(`^[a-zA-Z0-9]{16}$`)
+
+// Strip removes the fields matching the patterns from the document v and from its embedded documents, recognized by
+// their _key. A pattern is a dotted path of object keys, relative to the document, where a * segment matches any key
+// and an {id} segment matches a document or user id, e.g. "ownership.{id}" for the ownership given to each user.
+func Strip(v interface{}, patterns []string) {
+	docpath.EachObject(v, func(path string, obj map[string]interface{}) {
+		if _, ok := obj["_key"]; !ok {
+			return
+		}
+
+		for _, p := range patterns {
+			remove(obj, strings.Split(p, "."))
+		}
+	})
+}
+
+func remove(obj map[string]interface{}, segments []string) {
+	for k, v := range obj {
+		if !matches(segments[0], k) {
+			continue
+		}
+		if len(segments) == 1 {
+			delete(obj, k)
+		} else if child, ok := v.(map[string]interface{}); ok {
+			remove(child, segments[1:])
+		}
+	}
+}
+
+// Restore gives back to the document v the fields matching the patterns which it misses, taking them from its
+// previous version. The fields missing from the previous version too are set to null, the default value of the
+// document statistics, provided that their parent object exists. Patterns having * or {id} segments have no default.
+func Restore(v map[string]interface{}, previous map[string]interface{}, patterns []string) {
+	for _, p := range patterns {
+		segments := strings.Split(p, ".")
+		if previous != nil {
+			restore(v, previous, segments)
+		}
+
+		if strings.ContainsAny(p, "*{") {
+			continue
+		}
+		parent := v
+		for _, s := range segments[:len(segments)-1] {
+			child, ok := parent[s].(map[string]interface{})
+			if !ok {
+				parent = nil
+				break
+			}
+			parent = child
+		}
+		if parent == nil {
+			continue
+		}
+		if _, ok := parent[segments[len(segments)-1]]; !ok {
+			parent[segments[len(segments)-1]] = nil
+		}
+	}
+}
+
+func restore(v map[string]interface{}, previous map[string]interface{}, segments []string) {
+	keys := make([]string, 0, len(previous))
+	for k := range previous {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if !matches(segments[0], k) {
+			continue
+		}
+		if len(segments) == 1 {
+			if _, ok := v[k]; !ok {
+				v[k] = previous[k]
+			}
+			continue
+		}
+
+		prevChild, ok := previous[k].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		child, ok := v[k].(map[string]interface{})
+		if !ok {
+			if _, exists := v[k]; exists {
+				continue
+			}
+			child = map[string]interface{}{}
+			v[k] = child
+		}
+		restore(child, prevChild, segments[1:])
+	}
+}
+
+func matches(segment string, key string) bool {
+	switch segment {
+	case "*":
+		return true
+	case "{id}":
+		return idPattern.MatchString(key)
+	default:
+		return segment == key
+	}
+}
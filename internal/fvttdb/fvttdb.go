@@ -5,6 +5,7 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 	"log"
 )
 
@@ -24,6 +25,28 @@ func Open(path string) (*FvttDb, error) {
 	return &FvttDb{db: db}, nil
 }
 
+func OpenForWrite(path string) (*FvttDb, error) {
+	db, err := leveldb.OpenFile(path, &opt.Options{
+		ErrorIfMissing: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open db \"%s\": %s\n", path, err)
+	}
+
+	return &FvttDb{db: db}, nil
+}
+
+func Create(path string) (*FvttDb, error) {
+	db, err := leveldb.OpenFile(path, &opt.Options{
+		ErrorIfExist: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create db \"%s\": %s\n", path, err)
+	}
+
+	return &FvttDb{db: db}, nil
+}
+
 func (fvttDb *FvttDb) Close() {
 	if err := fvttDb.db.Close(); err != nil {
 		log.Fatalf("cannot close DB: %s", err)
@@ -50,3 +73,28 @@ func (fvttDb *FvttDb) Get(key string) ([]byte, error) {
 
 	return v, nil
 }
+
+func (fvttDb *FvttDb) Put(key string, value []byte) error {
+	if err := fvttDb.db.Put([]byte(key), value, nil); err != nil {
+		return fmt.Errorf("cannot put entry %s: %s\n", key, err)
+	}
+
+	return nil
+}
+
+func (fvttDb *FvttDb) Delete(key string) error {
+	if err := fvttDb.db.Delete([]byte(key), nil); err != nil {
+		return fmt.Errorf("cannot delete entry %s: %s\n", key, err)
+	}
+
+	return nil
+}
+
+// Compact compacts the whole database, so that its content is written in table files rather than in the journal.
+func (fvttDb *FvttDb) Compact() error {
+	if err := fvttDb.db.CompactRange(util.Range{}); err != nil {
+		return fmt.Errorf("cannot compact db: %s\n", err)
+	}
+
+	return nil
+}
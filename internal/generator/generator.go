@@ -0,0 +1,157 @@
+package generator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// idAlphabet are the characters of the ids generated by Foundry.
+const idAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// StableId returns a document id derived from the given parts, so that generating the same document again gives it
+// the same id.
+func StableId(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	n := new(big.Int).SetBytes(sum[:])
+	base := big.NewInt(int64(len(idAlphabet)))
+	mod := new(big.Int)
+
+	id := make([]byte, 16)
+	for i := range id {
+		n.DivMod(n, base, mod)
+		id[i] = idAlphabet[mod.Int64()]
+	}
+
+	return string(id)
+}
+
+// ReadData reads the rows of a CSV file, whose first line holds the column names, or of a YAML or JSON file holding a
+// list of objects.
+func ReadData(file string) ([]map[string]interface{}, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(filepath.Ext(file), ".csv") {
+		var rows []map[string]interface{}
+		if err := yaml.Unmarshal(data, &rows); err != nil {
+			return nil, fmt.Errorf("%s must hold a list of objects: %s\n", file, err)
+		}
+		return rows, nil
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff")))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	var rows []map[string]interface{}
+	for _, record := range records[1:] {
+		row := map[string]interface{}{}
+		for i, column := range records[0] {
+			if i < len(record) {
+				row[strings.TrimSpace(column)] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// Generator renders documents from a template.
+type Generator struct {
+	tmpl *template.Template
+	// Seed is mixed to the keys of the rows to give the ids of the documents, e.g. the name of the pack.
+	Seed string
+}
+
+// New parses a template of documents. It is a text/template rendering a document as YAML, or JSON, with the row as dot.
+// The template can use the following functions:
+//   - id: the id of the document, derived from the key of the row
+//   - childId "name": an id derived from the id of the document, e.g. for its embedded documents
+//   - json .value: the value as JSON, e.g. to quote strings
+//   - split .value "sep": the value split into a list
+//   - trim, lower and upper: the value trimmed, lowercased or uppercased
+func New(file string, seed string) (*Generator, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// The functions depending on the row are redefined when rendering it.
+	tmpl, err := template.New(filepath.Base(file)).Funcs(funcs("")).Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Generator{tmpl: tmpl, Seed: seed}, nil
+}
+
+// Render renders the document of a row, identified by key. The _id of the document is set if the template does not.
+func (g *Generator) Render(row map[string]interface{}, key string) (map[string]interface{}, error) {
+	id := StableId(g.Seed, key)
+
+	tmpl, err := g.tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Funcs(funcs(id)).Execute(&buf, row); err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		return nil, fmt.Errorf("the template does not give a valid document: %s\n", err)
+	}
+	if doc == nil {
+		return nil, errors.New("the template gives an empty document")
+	}
+	if _, ok := doc["_id"]; !ok {
+		doc["_id"] = id
+	}
+
+	return doc, nil
+}
+
+func funcs(id string) template.FuncMap {
+	return template.FuncMap{
+		"id":      func() string { return id },
+		"childId": func(name string) string { return StableId(id, name) },
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"split": func(v interface{}, sep string) []string {
+			s := strings.TrimSpace(fmt.Sprint(v))
+			if s == "" {
+				return []string{}
+			}
+			parts := strings.Split(s, sep)
+			for i := range parts {
+				parts[i] = strings.TrimSpace(parts[i])
+			}
+			return parts
+		},
+		"trim":  func(v interface{}) string { return strings.TrimSpace(fmt.Sprint(v)) },
+		"lower": func(v interface{}) string { return strings.ToLower(fmt.Sprint(v)) },
+		"upper": func(v interface{}) string { return strings.ToUpper(fmt.Sprint(v)) },
+	}
+}
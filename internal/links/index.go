@@ -0,0 +1,77 @@
+package links
+
+import (
+	"fmt"
+
+	"github.com/djlechuck/fvtt-packs/internal/documents"
+)
+
+type packIndex struct {
+	ids   map[string]string
+	names map[string]bool
+}
+
+// Index references the primary documents of the packs of a module, to validate the links pointing to them.
+type Index struct {
+	Module string
+	packs  map[string]*packIndex
+}
+
+func NewIndex(module string) *Index {
+	return &Index{Module: module, packs: map[string]*packIndex{}}
+}
+
+// AddPack registers a pack, even an empty one.
+func (i *Index) AddPack(pack string) {
+	if _, ok := i.packs[pack]; !ok {
+		i.packs[pack] = &packIndex{ids: map[string]string{}, names: map[string]bool{}}
+	}
+}
+
+// Add registers a primary document of a pack.
+func (i *Index) Add(pack string, collection string, id string, name string) {
+	i.AddPack(pack)
+	i.packs[pack].ids[id] = collection
+	if name != "" {
+		i.packs[pack].names[name] = true
+	}
+}
+
+// Checkable reports whether the link targets a pack the index knows about. When the module of the index is unknown,
+// links to unknown packs are considered external.
+func (i *Index) Checkable(l Link) bool {
+	if i.Module != "" {
+		return l.Module == i.Module
+	}
+	_, ok := i.packs[l.Pack]
+
+	return ok
+}
+
+// Validate returns an error describing why the link is dangling, or nil if its target exists.
+func (i *Index) Validate(l Link) error {
+	p, ok := i.packs[l.Pack]
+	if !ok {
+		return fmt.Errorf("unknown pack %s.%s", l.Module, l.Pack)
+	}
+
+	collection, ok := p.ids[l.Id]
+	if !ok {
+		if l.Legacy && p.names[l.Id] {
+			return nil
+		}
+		return fmt.Errorf("no document %s in pack %s", l.Id, l.Pack)
+	}
+
+	if l.DocumentName != "" {
+		expected, ok := documents.CollectionName(l.DocumentName)
+		if !ok {
+			return fmt.Errorf("unknown document type %s", l.DocumentName)
+		}
+		if expected != collection {
+			return fmt.Errorf("document %s of pack %s is not a %s", l.Id, l.Pack, l.DocumentName)
+		}
+	}
+
+	return nil
+}
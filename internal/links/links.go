@@ -0,0 +1,226 @@
+package links
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	uuidEnricher   = regexp.MustCompile(`@UUID\[(Compendium\.[^\]]+)\](\{[^}]*\})?`)
+	legacyEnricher = regexp.MustCompile(`@Compendium\[([^\]]+)\](\{[^}]*\})?`)
+)
+
+// Link is a reference to a compendium document, either from an enricher found in a rich-text field or from a field
+// holding a bare UUID such as _stats.compendiumSource.
+type Link struct {
+	Raw          string
+	Legacy       bool
+	Module       string
+	Pack         string
+	DocumentName string
+	Id           string
+	Embedded     string
+	Label        string
+}
+
+// Target designates a module, a pack of a module or a document of a pack, as written "module.pack.id".
+type Target struct {
+	Module string
+	Pack   string
+	Id     string
+}
+
+// Rule rewrites every link pointing inside From so that it points inside To.
+type Rule struct {
+	From Target
+	To   Target
+}
+
+// ParseTarget parses a "module[.pack[.id]]" string.
+func ParseTarget(s string) Target {
+	parts := strings.SplitN(s, ".", 3)
+	t := Target{Module: parts[0]}
+	if len(parts) > 1 {
+		t.Pack = parts[1]
+	}
+	if len(parts) > 2 {
+		t.Id = parts[2]
+	}
+
+	return t
+}
+
+// ParseUUID parses a bare compendium UUID like "Compendium.module.pack.Item.id". The second value is false if s is
+// not a compendium UUID.
+func ParseUUID(s string) (Link, bool) {
+	if !strings.HasPrefix(s, "Compendium.") {
+		return Link{}, false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(s, "Compendium."), ".")
+	if len(parts) < 3 {
+		return Link{}, false
+	}
+
+	l := Link{Raw: s, Module: parts[0], Pack: parts[1]}
+	rest := parts[2:]
+	if len(rest) == 1 {
+		// Pre-v11 UUIDs do not contain the document name.
+		l.Id = rest[0]
+		return l, true
+	}
+
+	l.DocumentName = rest[0]
+	l.Id = rest[1]
+	l.Embedded = strings.Join(rest[2:], ".")
+
+	return l, true
+}
+
+// Find returns every compendium link contained in the given text, UUID enrichers first.
+func Find(text string) []Link {
+	if !strings.Contains(text, "Compendium") {
+		return nil
+	}
+
+	var found []Link
+	for _, m := range uuidEnricher.FindAllString(text, -1) {
+		if l, ok := parseEnricher(m); ok {
+			found = append(found, l)
+		}
+	}
+	for _, m := range legacyEnricher.FindAllString(text, -1) {
+		if l, ok := parseEnricher(m); ok {
+			found = append(found, l)
+		}
+	}
+
+	return found
+}
+
+// parseEnricher parses a single @UUID or @Compendium enricher.
+func parseEnricher(raw string) (Link, bool) {
+	var l Link
+	var ok bool
+
+	if m := uuidEnricher.FindStringSubmatch(raw); m != nil {
+		l, ok = ParseUUID(m[1])
+	} else if m = legacyEnricher.FindStringSubmatch(raw); m != nil {
+		parts := strings.SplitN(m[1], ".", 3)
+		if ok = len(parts) == 3; ok {
+			l = Link{Legacy: true, Module: parts[0], Pack: parts[1], Id: parts[2]}
+		}
+	}
+	if !ok {
+		return Link{}, false
+	}
+
+	l.Raw = raw
+	if i := strings.Index(raw, "]{"); i >= 0 {
+		l.Label = strings.TrimSuffix(raw[i+2:], "}")
+	}
+
+	return l, true
+}
+
+// Matches reports whether the link points inside the target.
+func (l Link) Matches(t Target) bool {
+	if l.Module != t.Module {
+		return false
+	}
+	if t.Pack != "" && l.Pack != t.Pack {
+		return false
+	}
+
+	return t.Id == "" || l.Id == t.Id
+}
+
+// UUID returns the compendium UUID of the linked document, without the enricher syntax.
+func (l Link) UUID() string {
+	if l.Legacy {
+		return l.Module + "." + l.Pack + "." + l.Id
+	}
+
+	parts := []string{"Compendium", l.Module, l.Pack}
+	if l.DocumentName != "" {
+		parts = append(parts, l.DocumentName)
+	}
+	parts = append(parts, l.Id)
+	if l.Embedded != "" {
+		parts = append(parts, l.Embedded)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// String returns the link as it must be written in a document.
+func (l Link) String() string {
+	if strings.HasPrefix(l.Raw, "Compendium.") {
+		return l.UUID()
+	}
+
+	s := "@UUID[" + l.UUID() + "]"
+	if l.Legacy {
+		s = "@Compendium[" + l.UUID() + "]"
+	}
+	if l.Label != "" || strings.HasSuffix(l.Raw, "{}") {
+		s += "{" + l.Label + "}"
+	}
+
+	return s
+}
+
+// apply returns the link rewritten by the first matching rule.
+func (l Link) apply(rules []Rule) (Link, bool) {
+	for _, r := range rules {
+		if !l.Matches(r.From) {
+			continue
+		}
+
+		l.Module = r.To.Module
+		if r.To.Pack != "" {
+			l.Pack = r.To.Pack
+		}
+		if r.To.Id != "" {
+			l.Id = r.To.Id
+		}
+
+		return l, true
+	}
+
+	return l, false
+}
+
+// Rewrite rewrites the links of the given text according to the rules. It returns the new text and the number of
+// rewritten links. A text consisting of a bare UUID is rewritten as well.
+func Rewrite(text string, rules []Rule) (string, int) {
+	if l, ok := ParseUUID(text); ok {
+		if nl, ok := l.apply(rules); ok {
+			return nl.String(), 1
+		}
+		return text, 0
+	}
+
+	if !strings.Contains(text, "Compendium") {
+		return text, 0
+	}
+
+	count := 0
+	replace := func(raw string) string {
+		l, ok := parseEnricher(raw)
+		if !ok {
+			return raw
+		}
+		nl, ok := l.apply(rules)
+		if !ok {
+			return raw
+		}
+		count++
+
+		return nl.String()
+	}
+	text = uuidEnricher.ReplaceAllStringFunc(text, replace)
+	text = legacyEnricher.ReplaceAllStringFunc(text, replace)
+
+	return text, count
+}
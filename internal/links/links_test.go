@@ -0,0 +1,226 @@
+package links
+
+import (
+	"testing"
+)
+
+func TestParseUUID(t *testing.T) {
+	tests := []struct {
+		uuid     string
+		ok       bool
+		expected Link
+	}{
+		{"Compendium.my-mod.items.Item.aaaaaaaaaaaaaaaa", true,
+			Link{Module: "my-mod", Pack: "items", DocumentName: "Item", Id: "aaaaaaaaaaaaaaaa"}},
+		{"Compendium.my-mod.actors.Actor.aaaaaaaaaaaaaaaa.Item.bbbbbbbbbbbbbbbb", true,
+			Link{Module: "my-mod", Pack: "actors", DocumentName: "Actor", Id: "aaaaaaaaaaaaaaaa", Embedded: "Item.bbbbbbbbbbbbbbbb"}},
+		{"Compendium.my-mod.items.aaaaaaaaaaaaaaaa", true, Link{Module: "my-mod", Pack: "items", Id: "aaaaaaaaaaaaaaaa"}},
+		{"Compendium.my-mod.items", false, Link{}},
+		{"Item.aaaaaaaaaaaaaaaa", false, Link{}},
+		{"", false, Link{}},
+	}
+
+	for _, tt := range tests {
+		l, ok := ParseUUID(tt.uuid)
+		if ok != tt.ok {
+			t.Errorf("ParseUUID(%s) gives %t, expected %t", tt.uuid, ok, tt.ok)
+			continue
+		}
+		if ok {
+			tt.expected.Raw = tt.uuid
+		}
+		if l != tt.expected {
+			t.Errorf("ParseUUID(%s) = %+v, expected %+v", tt.uuid, l, tt.expected)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	text := `<p>@Compendium[my-mod.items.Dagger]{Old} and @UUID[Compendium.my-mod.items.Item.aaaaaaaaaaaaaaaa]{Sword},
+		@UUID[Compendium.other.spells.Item.bbbbbbbbbbbbbbbb], @UUID[Actor.cccccccccccccccc]{World}
+		and @Compendium[broken]{Broken}</p>`
+
+	expected := []Link{
+		{Raw: "@UUID[Compendium.my-mod.items.Item.aaaaaaaaaaaaaaaa]{Sword}", Module: "my-mod", Pack: "items",
+			DocumentName: "Item", Id: "aaaaaaaaaaaaaaaa", Label: "Sword"},
+		{Raw: "@UUID[Compendium.other.spells.Item.bbbbbbbbbbbbbbbb]", Module: "other", Pack: "spells",
+			DocumentName: "Item", Id: "bbbbbbbbbbbbbbbb"},
+		{Raw: "@Compendium[my-mod.items.Dagger]{Old}", Legacy: true, Module: "my-mod", Pack: "items", Id: "Dagger",
+			Label: "Old"},
+	}
+
+	found := Find(text)
+	if len(found) != len(expected) {
+		t.Fatalf("Find gives %+v, expected %+v", found, expected)
+	}
+	for i := range expected {
+		if found[i] != expected[i] {
+			t.Errorf("Find gives %+v, expected %+v", found[i], expected[i])
+		}
+	}
+
+	if found := Find("<p>No link, @UUID[Item.aaaaaaaaaaaaaaaa]</p>"); found != nil {
+		t.Errorf("Find gives %+v, expected nothing", found)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected Target
+	}{
+		{"my-mod", Target{Module: "my-mod"}},
+		{"my-mod.items", Target{Module: "my-mod", Pack: "items"}},
+		{"my-mod.items.aaaaaaaaaaaaaaaa", Target{Module: "my-mod", Pack: "items", Id: "aaaaaaaaaaaaaaaa"}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseTarget(tt.s); got != tt.expected {
+			t.Errorf("ParseTarget(%s) = %+v, expected %+v", tt.s, got, tt.expected)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		text     string
+		rules    []Rule
+		expected string
+		count    int
+	}{
+		{
+			text:     "See @UUID[Compendium.old-mod.items.Item.aaaaaaaaaaaaaaaa]{Sword}.",
+			rules:    []Rule{{From: ParseTarget("old-mod"), To: ParseTarget("new-mod")}},
+			expected: "See @UUID[Compendium.new-mod.items.Item.aaaaaaaaaaaaaaaa]{Sword}.",
+			count:    1,
+		},
+		{
+			text:     "@UUID[Compendium.my-mod.items.Item.aaaaaaaaaaaaaaaa]{} @UUID[Compendium.my-mod.spells.Item.bbbbbbbbbbbbbbbb]",
+			rules:    []Rule{{From: ParseTarget("my-mod.items"), To: ParseTarget("my-mod.equipment")}},
+			expected: "@UUID[Compendium.my-mod.equipment.Item.aaaaaaaaaaaaaaaa]{} @UUID[Compendium.my-mod.spells.Item.bbbbbbbbbbbbbbbb]",
+			count:    1,
+		},
+		{
+			text: "@UUID[Compendium.my-mod.items.Item.aaaaaaaaaaaaaaaa] @UUID[Compendium.my-mod.items.Item.bbbbbbbbbbbbbbbb]",
+			rules: []Rule{{
+				From: ParseTarget("my-mod.items.aaaaaaaaaaaaaaaa"),
+				To:   ParseTarget("my-mod.weapons.cccccccccccccccc"),
+			}},
+			expected: "@UUID[Compendium.my-mod.weapons.Item.cccccccccccccccc] @UUID[Compendium.my-mod.items.Item.bbbbbbbbbbbbbbbb]",
+			count:    1,
+		},
+		{
+			text:     "@UUID[Compendium.my-mod.actors.Actor.aaaaaaaaaaaaaaaa.Item.bbbbbbbbbbbbbbbb]{Claw}",
+			rules:    []Rule{{From: ParseTarget("my-mod.actors"), To: ParseTarget("my-mod.monsters")}},
+			expected: "@UUID[Compendium.my-mod.monsters.Actor.aaaaaaaaaaaaaaaa.Item.bbbbbbbbbbbbbbbb]{Claw}",
+			count:    1,
+		},
+		{
+			text:     "@Compendium[old-mod.items.Dagger]{Dagger}",
+			rules:    []Rule{{From: ParseTarget("old-mod"), To: ParseTarget("new-mod")}},
+			expected: "@Compendium[new-mod.items.Dagger]{Dagger}",
+			count:    1,
+		},
+		{
+			text:     "Compendium.old-mod.items.Item.aaaaaaaaaaaaaaaa",
+			rules:    []Rule{{From: ParseTarget("old-mod"), To: ParseTarget("new-mod")}},
+			expected: "Compendium.new-mod.items.Item.aaaaaaaaaaaaaaaa",
+			count:    1,
+		},
+		{
+			text: "@UUID[Compendium.a.items.Item.aaaaaaaaaaaaaaaa]",
+			rules: []Rule{
+				{From: ParseTarget("a"), To: ParseTarget("b")},
+				{From: ParseTarget("a.items"), To: ParseTarget("c.items")},
+			},
+			expected: "@UUID[Compendium.b.items.Item.aaaaaaaaaaaaaaaa]",
+			count:    1,
+		},
+		{
+			text:     "@UUID[Compendium.other.items.Item.aaaaaaaaaaaaaaaa] Compendium of old-mod",
+			rules:    []Rule{{From: ParseTarget("old-mod"), To: ParseTarget("new-mod")}},
+			expected: "@UUID[Compendium.other.items.Item.aaaaaaaaaaaaaaaa] Compendium of old-mod",
+			count:    0,
+		},
+		{
+			text:     "Compendium.other.items.Item.aaaaaaaaaaaaaaaa",
+			rules:    []Rule{{From: ParseTarget("old-mod"), To: ParseTarget("new-mod")}},
+			expected: "Compendium.other.items.Item.aaaaaaaaaaaaaaaa",
+			count:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		got, count := Rewrite(tt.text, tt.rules)
+		if got != tt.expected || count != tt.count {
+			t.Errorf("Rewrite(%s) = %s, %d, expected %s, %d", tt.text, got, count, tt.expected, tt.count)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	idx := NewIndex("my-mod")
+	idx.Add("items", "items", "aaaaaaaaaaaaaaaa", "Dagger")
+	idx.Add("actors", "actors", "bbbbbbbbbbbbbbbb", "Goblin")
+	idx.AddPack("empty")
+
+	tests := []struct {
+		uuid  string
+		error string
+	}{
+		{"Compendium.my-mod.items.Item.aaaaaaaaaaaaaaaa", ""},
+		{"Compendium.my-mod.items.aaaaaaaaaaaaaaaa", ""},
+		{"Compendium.my-mod.actors.Actor.bbbbbbbbbbbbbbbb.Item.cccccccccccccccc", ""},
+		{"Compendium.my-mod.spells.Item.aaaaaaaaaaaaaaaa", "unknown pack my-mod.spells"},
+		{"Compendium.my-mod.empty.Item.aaaaaaaaaaaaaaaa", "no document aaaaaaaaaaaaaaaa in pack empty"},
+		{"Compendium.my-mod.items.Item.cccccccccccccccc", "no document cccccccccccccccc in pack items"},
+		{"Compendium.my-mod.items.Actor.aaaaaaaaaaaaaaaa", "document aaaaaaaaaaaaaaaa of pack items is not a Actor"},
+		{"Compendium.my-mod.items.Thing.aaaaaaaaaaaaaaaa", "unknown document type Thing"},
+	}
+
+	for _, tt := range tests {
+		l, _ := ParseUUID(tt.uuid)
+		err := idx.Validate(l)
+		if tt.error == "" && err != nil || tt.error != "" && (err == nil || err.Error() != tt.error) {
+			t.Errorf("Validate(%s) = %v, expected %s", tt.uuid, err, tt.error)
+		}
+	}
+
+	legacy := []struct {
+		text  string
+		error string
+	}{
+		{"@Compendium[my-mod.items.Dagger]", ""},
+		{"@Compendium[my-mod.items.aaaaaaaaaaaaaaaa]", ""},
+		{"@Compendium[my-mod.items.Sword]", "no document Sword in pack items"},
+	}
+	for _, tt := range legacy {
+		l := Find(tt.text)[0]
+		err := idx.Validate(l)
+		if tt.error == "" && err != nil || tt.error != "" && (err == nil || err.Error() != tt.error) {
+			t.Errorf("Validate(%s) = %v, expected %s", tt.text, err, tt.error)
+		}
+	}
+}
+
+func TestCheckable(t *testing.T) {
+	tests := []struct {
+		module   string
+		uuid     string
+		expected bool
+	}{
+		{"my-mod", "Compendium.my-mod.spells.Item.aaaaaaaaaaaaaaaa", true},
+		{"my-mod", "Compendium.dnd5e.items.Item.aaaaaaaaaaaaaaaa", false},
+		{"", "Compendium.any.items.Item.aaaaaaaaaaaaaaaa", true},
+		{"", "Compendium.any.spells.Item.aaaaaaaaaaaaaaaa", false},
+	}
+
+	for _, tt := range tests {
+		idx := NewIndex(tt.module)
+		idx.AddPack("items")
+		l, _ := ParseUUID(tt.uuid)
+		if got := idx.Checkable(l); got != tt.expected {
+			t.Errorf("Checkable(%s) with the module %q = %t, expected %t", tt.uuid, tt.module, got, tt.expected)
+		}
+	}
+}
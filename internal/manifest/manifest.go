@@ -0,0 +1,384 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Files are the manifest file names, in lookup order.
+var Files = []string{"module.json", "system.json"}
+
+type Pack struct {
+	Name      string                 `json:"name"`
+	Label     string                 `json:"label"`
+	Path      string                 `json:"path,omitempty"`
+	Type      string                 `json:"type"`
+	System    string                 `json:"system,omitempty"`
+	Ownership map[string]string      `json:"ownership,omitempty"`
+	Flags     map[string]interface{} `json:"flags,omitempty"`
+}
+
+type PackFolder struct {
+	Name    string       `json:"name"`
+	Sorting string       `json:"sorting,omitempty"`
+	Color   string       `json:"color,omitempty"`
+	Packs   []string     `json:"packs"`
+	Folders []PackFolder `json:"folders,omitempty"`
+}
+
+// Manifest is the module.json or system.json file of a package.
+type Manifest struct {
+	File        string `json:"-"`
+	raw         []byte
+	Id          string       `json:"id"`
+	Title       string       `json:"title"`
+	Version     string       `json:"version"`
+	ManifestURL string       `json:"manifest"`
+	Download    string       `json:"download"`
+	Packs       []Pack       `json:"packs"`
+	PackFolders []PackFolder `json:"packFolders"`
+}
+
+// Load reads the manifest inside the given directory. The returned error wraps os.ErrNotExist when the directory
+// contains no manifest.
+func Load(dir string) (*Manifest, error) {
+	for _, name := range Files {
+		file := filepath.Join(dir, name)
+		data, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read manifest \"%s\": %s\n", file, err)
+		}
+
+		m := &Manifest{File: file, raw: data}
+		if err := json.Unmarshal(data, m); err != nil {
+			return nil, fmt.Errorf("cannot parse manifest \"%s\": %s\n", file, err)
+		}
+
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("no manifest found in \"%s\": %w", dir, os.ErrNotExist)
+}
+
+// Exists reports whether the given directory contains a manifest.
+func Exists(dir string) bool {
+	for _, name := range Files {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Save writes the manifest back to its file. Only the fields which have been modified are rewritten: the other fields,
+// their order and their formatting are kept as they are.
+func (m *Manifest) Save() error {
+	fields, err := orderedFields(m.raw)
+	if err != nil {
+		return fmt.Errorf("cannot parse manifest \"%s\": %s\n", m.File, err)
+	}
+
+	var original Manifest
+	if err := json.Unmarshal(m.raw, &original); err != nil {
+		return fmt.Errorf("cannot parse manifest \"%s\": %s\n", m.File, err)
+	}
+
+	var packs interface{} = m.Packs
+	if i := fieldIndex(fields, "packs"); i >= 0 {
+		if packs, err = mergePacks(fields[i].value, m.Packs); err != nil {
+			return fmt.Errorf("cannot parse manifest \"%s\": %s\n", m.File, err)
+		}
+	}
+
+	indent := detectIndent(m.raw)
+	updates := []struct {
+		key      string
+		value    interface{}
+		original interface{}
+		empty    bool
+	}{
+		{"id", m.Id, original.Id, m.Id == ""},
+		{"title", m.Title, original.Title, m.Title == ""},
+		{"version", m.Version, original.Version, m.Version == ""},
+		{"manifest", m.ManifestURL, original.ManifestURL, m.ManifestURL == ""},
+		{"download", m.Download, original.Download, m.Download == ""},
+		{"packs", packs, original.Packs, len(m.Packs) == 0},
+		{"packFolders", m.PackFolders, original.PackFolders, len(m.PackFolders) == 0},
+	}
+
+	for _, u := range updates {
+		current, _ := json.Marshal(u.value)
+		if u.key == "packs" {
+			current, _ = json.Marshal(m.Packs)
+		}
+		previous, _ := json.Marshal(u.original)
+		if bytes.Equal(current, previous) {
+			continue
+		}
+
+		value, err := json.MarshalIndent(u.value, indent, indent)
+		if err != nil {
+			return fmt.Errorf("cannot encode manifest field %s: %s\n", u.key, err)
+		}
+
+		i := fieldIndex(fields, u.key)
+		if i < 0 {
+			if u.empty {
+				continue
+			}
+			fields = append(fields, field{key: u.key})
+			i = len(fields) - 1
+		}
+		fields[i].value = value
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	for i, f := range fields {
+		key, _ := json.Marshal(f.key)
+		buf.WriteString(indent)
+		buf.Write(key)
+		buf.WriteString(": ")
+		buf.Write(f.value)
+		if i < len(fields)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}\n")
+
+	if err := os.WriteFile(m.File, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("cannot write manifest \"%s\": %s\n", m.File, err)
+	}
+	m.raw = buf.Bytes()
+
+	return nil
+}
+
+type field struct {
+	key   string
+	value json.RawMessage
+}
+
+// object is a JSON object whose fields are encoded in order.
+type object []field
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(f.value)
+	}
+	buf.WriteString("}")
+
+	return buf.Bytes(), nil
+}
+
+// mergePacks returns the packs to write in place of the given packs array of the manifest. The packs already written
+// keep their fields unknown to Pack, e.g. banner, and the order of their fields; only the fields of Pack are updated.
+func mergePacks(raw json.RawMessage, packs []Pack) ([]object, error) {
+	var written []json.RawMessage
+	if err := json.Unmarshal(raw, &written); err != nil {
+		return nil, err
+	}
+	byName := map[string][]field{}
+	for _, data := range written {
+		fields, err := orderedFields(data)
+		if err != nil {
+			return nil, err
+		}
+		var p Pack
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, err
+		}
+		byName[p.Name] = fields
+	}
+
+	var known []string
+	t := reflect.TypeOf(Pack{})
+	for i := 0; i < t.NumField(); i++ {
+		known = append(known, strings.Split(t.Field(i).Tag.Get("json"), ",")[0])
+	}
+
+	merged := make([]object, 0, len(packs))
+	for _, p := range packs {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := orderedFields(data)
+		if err != nil {
+			return nil, err
+		}
+
+		fields, ok := byName[p.Name]
+		if !ok {
+			merged = append(merged, updated)
+			continue
+		}
+
+		var o object
+		for _, f := range fields {
+			if i := fieldIndex(updated, f.key); i >= 0 {
+				if jsonEqual(f.value, updated[i].value) {
+					o = append(o, f)
+				} else {
+					o = append(o, updated[i])
+				}
+			} else if !slices.Contains(known, f.key) {
+				o = append(o, f)
+			}
+		}
+		for _, f := range updated {
+			if fieldIndex(o, f.key) < 0 {
+				o = append(o, f)
+			}
+		}
+		merged = append(merged, o)
+	}
+
+	return merged, nil
+}
+
+// jsonEqual reports whether two JSON values are the same, whatever their formatting.
+func jsonEqual(a json.RawMessage, b json.RawMessage) bool {
+	var bufA, bufB bytes.Buffer
+	if json.Compact(&bufA, a) != nil || json.Compact(&bufB, b) != nil {
+		return false
+	}
+
+	return bytes.Equal(bufA.Bytes(), bufB.Bytes())
+}
+
+// orderedFields returns the fields of a JSON object in the order they are written.
+func orderedFields(data []byte) ([]field, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, errors.New("the manifest is not a JSON object")
+	}
+
+	var fields []field
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var f field
+		f.key, _ = t.(string)
+		if err := dec.Decode(&f.value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+func fieldIndex(fields []field, key string) int {
+	for i, f := range fields {
+		if f.key == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// detectIndent returns the indentation of the first indented line of the JSON data, two spaces by default.
+func detectIndent(data []byte) string {
+	for _, line := range bytes.Split(data, []byte("\n"))[1:] {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) > 0 && len(trimmed) < len(line) {
+			return string(line[:len(line)-len(trimmed)])
+		}
+	}
+
+	return "  "
+}
+
+// IsSystem reports whether the manifest is the one of a system.
+func (m *Manifest) IsSystem() bool {
+	return filepath.Base(m.File) == "system.json"
+}
+
+// DatabasePath returns the path of the LevelDB directory of the pack, relative to the package root.
+func (p Pack) DatabasePath() string {
+	if p.Path == "" {
+		return filepath.Join("packs", p.Name)
+	}
+
+	// Before v11, packs were NeDB files: their LevelDB version lives next to them, without extension.
+	return filepath.FromSlash(strings.TrimSuffix(strings.TrimPrefix(p.Path, "/"), ".db"))
+}
+
+// FolderPath returns the names of the pack folders containing the given pack, outermost first. It returns nil if the
+// pack is not inside a pack folder.
+func (m *Manifest) FolderPath(pack string) []string {
+	return folderPath(m.PackFolders, pack)
+}
+
+func folderPath(folders []PackFolder, pack string) []string {
+	for _, f := range folders {
+		for _, p := range f.Packs {
+			if p == pack {
+				return []string{f.Name}
+			}
+		}
+		if sub := folderPath(f.Folders, pack); sub != nil {
+			return append([]string{f.Name}, sub...)
+		}
+	}
+
+	return nil
+}
+
+// AddToFolder puts the pack inside the pack folder designated by the names of its parents, creating the missing folders.
+func (m *Manifest) AddToFolder(path []string, pack string) {
+	m.PackFolders = addToFolder(m.PackFolders, path, pack)
+}
+
+func addToFolder(folders []PackFolder, path []string, pack string) []PackFolder {
+	i := 0
+	for ; i < len(folders) && folders[i].Name != path[0]; i++ {
+	}
+	if i == len(folders) {
+		folders = append(folders, PackFolder{Name: path[0], Packs: []string{}})
+	}
+
+	if len(path) == 1 {
+		folders[i].Packs = append(folders[i].Packs, pack)
+	} else {
+		folders[i].Folders = addToFolder(folders[i].Folders, path[1:], pack)
+	}
+
+	return folders
+}
+
+// Pack returns the pack with the given name, or nil if the manifest does not declare it.
+func (m *Manifest) Pack(name string) *Pack {
+	for i := range m.Packs {
+		if m.Packs[i].Name == name {
+			return &m.Packs[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveKeepsUnknownPackFields(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "module.json")
+	original := `{
+  "id": "my-module",
+  "packs": [
+    {
+      "name": "items",
+      "label": "Items",
+      "banner": "banner.webp",
+      "type": "Item"
+    }
+  ]
+}
+`
+	if err := os.WriteFile(file, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Packs[0].Label = "Equipment"
+	m.Packs = append(m.Packs, Pack{Name: "spells", Label: "Spells", Type: "Item"})
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "id": "my-module",
+  "packs": [
+    {
+      "name": "items",
+      "label": "Equipment",
+      "banner": "banner.webp",
+      "type": "Item"
+    },
+    {
+      "name": "spells",
+      "label": "Spells",
+      "type": "Item"
+    }
+  ]
+}
+`
+	if string(data) != expected {
+		t.Errorf("got\n%s\nexpected\n%s", data, expected)
+	}
+}
+
+func TestSaveUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "system.json")
+	original := "{\n\t\"id\": \"my-system\",\n\t\"packs\": [{\"name\": \"items\", \"label\": \"Items\", \"type\": \"Item\"}]\n}\n"
+	if err := os.WriteFile(file, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(file)
+	if string(data) != original {
+		t.Errorf("got\n%s\nexpected\n%s", data, original)
+	}
+}
@@ -0,0 +1,228 @@
+package packer
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+	"github.com/djlechuck/fvtt-packs/internal/fvttdb"
+)
+
+var idPattern = regexp.MustCompile(`^[a-zA-Z0-9]{16}$`)
+
+// Entry is a LevelDB entry built from a source document.
+type Entry struct {
+	Key   string
+	Value map[string]interface{}
+	// File is the source file the entry comes from.
+	File string
+}
+
+// Collection returns the collection of the entry, e.g. "actors.items".
+func (e Entry) Collection() string {
+	parts := strings.Split(e.Key, "!")
+	if len(parts) < 3 {
+		return ""
+	}
+
+	return parts[1]
+}
+
+// IsPrimary reports whether the entry is a primary document rather than an embedded one.
+func (e Entry) IsPrimary() bool {
+	return !strings.Contains(e.Collection(), ".")
+}
+
+// Flatten splits a source document into its LevelDB entries. Embedded documents, recognized by their _key, are stored
+// as separate entries and replaced by their ids in their parent.
+func Flatten(doc map[string]interface{}, file string) ([]Entry, error) {
+	key, ok := doc["_key"].(string)
+	if !ok || key == "" {
+		return nil, fmt.Errorf("%s: missing _key\n", file)
+	}
+	delete(doc, "_key")
+
+	entries := []Entry{{Key: key, Value: doc, File: file}}
+	for field, v := range doc {
+		children, ok := embeddedDocuments(v)
+		if !ok {
+			continue
+		}
+
+		ids := make([]interface{}, 0, len(children))
+		for _, child := range children {
+			ids = append(ids, child["_id"])
+			childEntries, err := Flatten(child, file)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, childEntries...)
+		}
+		doc[field] = ids
+	}
+
+	return entries, nil
+}
+
+// Hydrate reverses Flatten: it returns the primary documents of the entries, in their order, with their _key and their
+// embedded documents in place of their ids. The entries are left untouched.
+func Hydrate(entries []Entry) []map[string]interface{} {
+	byKey := make(map[string]map[string]interface{}, len(entries))
+	for _, e := range entries {
+		byKey[e.Key] = e.Value
+	}
+
+	var docs []map[string]interface{}
+	for _, e := range entries {
+		if e.IsPrimary() {
+			docs = append(docs, hydrate(byKey, e.Key, e.Value))
+		}
+	}
+
+	return docs
+}
+
+func hydrate(byKey map[string]map[string]interface{}, key string, value map[string]interface{}) map[string]interface{} {
+	parts := strings.Split(key, "!")
+	doc := make(map[string]interface{}, len(value)+1)
+	for field, v := range value {
+		doc[field] = v
+
+		ids, ok := v.([]interface{})
+		if !ok || len(ids) == 0 || len(parts) < 3 {
+			continue
+		}
+		children := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			s, _ := id.(string)
+			childKey := "!" + parts[1] + "." + field + "!" + parts[2] + "." + s
+			child, ok := byKey[childKey]
+			if !ok {
+				break
+			}
+			children = append(children, hydrate(byKey, childKey, child))
+		}
+		if len(children) == len(ids) {
+			doc[field] = children
+		}
+	}
+	doc["_key"] = key
+
+	return doc
+}
+
+// embeddedDocuments returns the documents of v if it is a non-empty list of documents having a _key.
+func embeddedDocuments(v interface{}) ([]map[string]interface{}, bool) {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, false
+	}
+
+	docs := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		doc, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if key, ok := doc["_key"].(string); !ok || !strings.HasPrefix(key, "!") {
+			return nil, false
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs, true
+}
+
+// Validate checks the entries of a pack whose primary documents belong to the given collection, or to any collection
+// if it is empty. It returns every problem found.
+func Validate(entries []Entry, collection string) []error {
+	var errs []error
+	keys := map[string]string{}
+	for _, e := range entries {
+		if previous, ok := keys[e.Key]; ok {
+			errs = append(errs, fmt.Errorf("%s: %s is already defined in %s", e.File, e.Key, previous))
+			continue
+		}
+		keys[e.Key] = e.File
+	}
+
+	for _, e := range entries {
+		parts := strings.Split(e.Key, "!")
+		if len(parts) != 3 || parts[0] != "" {
+			errs = append(errs, fmt.Errorf("%s: invalid key %s", e.File, e.Key))
+			continue
+		}
+
+		ids := strings.Split(parts[2], ".")
+		if len(ids) != len(strings.Split(parts[1], ".")) {
+			errs = append(errs, fmt.Errorf("%s: invalid key %s", e.File, e.Key))
+			continue
+		}
+
+		id, _ := e.Value["_id"].(string)
+		if !idPattern.MatchString(id) {
+			errs = append(errs, fmt.Errorf("%s: %s has an invalid _id \"%s\"", e.File, e.Key, id))
+		} else if id != ids[len(ids)-1] {
+			errs = append(errs, fmt.Errorf("%s: %s has the _id %s of another document", e.File, e.Key, id))
+		}
+
+		if !e.IsPrimary() {
+			continue
+		}
+
+		if collection != "" && parts[1] != collection && parts[1] != "folders" {
+			errs = append(errs, fmt.Errorf("%s: %s is not a document of the %s of the pack", e.File, e.Key, collection))
+		}
+		if name, _ := e.Value["name"].(string); name == "" {
+			errs = append(errs, fmt.Errorf("%s: %s has no name", e.File, e.Key))
+		}
+		if folder, ok := e.Value["folder"].(string); ok && folder != "" {
+			if _, ok := keys["!folders!"+folder]; !ok {
+				errs = append(errs, fmt.Errorf("%s: %s is inside the unknown folder %s", e.File, e.Key, folder))
+			}
+		}
+	}
+
+	return errs
+}
+
+// Write replaces the content of the LevelDB at the given path by the entries.
+func Write(path string, entries []Entry) error {
+	tmp := path + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+
+	db, err := fvttdb.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	for _, e := range entries {
+		v, err := docpath.Encode(e.Value)
+		if err != nil {
+			db.Close()
+			return fmt.Errorf("cannot encode %s: %s\n", e.Key, err)
+		}
+		if err := db.Put(e.Key, v); err != nil {
+			db.Close()
+			return err
+		}
+	}
+
+	if err := db.Compact(); err != nil {
+		db.Close()
+		return err
+	}
+	db.Close()
+
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,385 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+// Patch changes a document decoded from JSON. Apply returns the changed document, leaving the given one untouched, so
+// that a patch failing halfway changes nothing.
+type Patch interface {
+	Apply(doc interface{}) (interface{}, error)
+}
+
+// Operation is an operation of a JSON Patch (RFC 6902).
+type Operation struct {
+	Op    string
+	Path  string
+	From  string
+	Value interface{}
+}
+
+// JSONPatch is a JSON Patch (RFC 6902): a list of operations applied in order, e.g.
+// [{"op": "move", "from": "/system/dmg", "path": "/system/damage"}].
+type JSONPatch []Operation
+
+// MergePatch is a JSON Merge Patch (RFC 7396): an object whose fields replace the fields of the document, objects being
+// merged recursively and null removing a field, e.g. {"flags": {"old-module": null, "new-module": {"rare": true}}}.
+type MergePatch struct {
+	Value interface{}
+}
+
+var errNotFound = errors.New("path not found")
+
+// ParseJSONPatch reads a JSON Patch decoded from JSON or YAML.
+func ParseJSONPatch(v interface{}) (JSONPatch, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("a JSON Patch is a list of operations")
+	}
+
+	ops := make(JSONPatch, 0, len(list))
+	for i, e := range list {
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operation %d is not an object", i+1)
+		}
+
+		op := Operation{Value: m["value"]}
+		op.Op, _ = m["op"].(string)
+		op.Path, ok = m["path"].(string)
+		if !ok {
+			return nil, fmt.Errorf("operation %d has no path", i+1)
+		}
+		switch op.Op {
+		case "add", "replace", "test":
+			if _, ok := m["value"]; !ok {
+				return nil, fmt.Errorf("operation %d has no value", i+1)
+			}
+		case "move", "copy":
+			if op.From, ok = m["from"].(string); !ok {
+				return nil, fmt.Errorf("operation %d has no from", i+1)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("operation %d has an unknown op \"%s\"", i+1, op.Op)
+		}
+		if _, err := parsePointer(op.Path); err != nil {
+			return nil, fmt.Errorf("operation %d: %s", i+1, err)
+		}
+		if _, err := parsePointer(op.From); err != nil {
+			return nil, fmt.Errorf("operation %d: %s", i+1, err)
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+func (p JSONPatch) Apply(doc interface{}) (interface{}, error) {
+	doc = deepCopy(doc)
+	for _, op := range p {
+		var err error
+		if doc, err = op.apply(doc); err != nil {
+			if op.From != "" {
+				return nil, fmt.Errorf("%s %s to %s: %s", op.Op, op.From, op.Path, err)
+			}
+			return nil, fmt.Errorf("%s %s: %s", op.Op, op.Path, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func (op Operation) apply(doc interface{}) (interface{}, error) {
+	path, _ := parsePointer(op.Path)
+	from, _ := parsePointer(op.From)
+
+	switch op.Op {
+	case "add":
+		return add(doc, path, deepCopy(op.Value))
+	case "remove":
+		if len(path) == 0 {
+			return nil, errors.New("cannot remove the document")
+		}
+		return modify(doc, path, remove)
+	case "replace":
+		if _, err := get(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return deepCopy(op.Value), nil
+		}
+		return modify(doc, path, replace(deepCopy(op.Value)))
+	case "move":
+		if op.Path == op.From {
+			return doc, nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, errors.New("cannot move a value into itself")
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if len(from) == 0 {
+			return nil, errors.New("cannot move the document")
+		}
+		if doc, err = modify(doc, from, remove); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "copy":
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(value))
+	case "test":
+		value, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(value, op.Value) {
+			return nil, errors.New("test failed")
+		}
+		return doc, nil
+	}
+
+	return nil, fmt.Errorf("unknown op %s", op.Op)
+}
+
+func (p MergePatch) Apply(doc interface{}) (interface{}, error) {
+	return merge(deepCopy(doc), p.Value), nil
+}
+
+func merge(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return deepCopy(patch)
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = merge(t[k], v)
+		}
+	}
+
+	return t
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901), e.g. "/system/damage/parts/0", into its unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path \"%s\", it must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+
+	return tokens, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, t := range path {
+		switch val := doc.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if doc, ok = val[t]; !ok {
+				return nil, errNotFound
+			}
+		case []interface{}:
+			i, err := arrayIndex(t, len(val)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = val[i]
+		default:
+			return nil, errNotFound
+		}
+	}
+
+	return doc, nil
+}
+
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return modify(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch val := parent.(type) {
+		case map[string]interface{}:
+			val[key] = value
+			return val, nil
+		case []interface{}:
+			i := len(val)
+			if key != "-" {
+				var err error
+				if i, err = arrayIndex(key, len(val)); err != nil {
+					return nil, err
+				}
+			}
+			val = append(val, nil)
+			copy(val[i+1:], val[i:])
+			val[i] = value
+			return val, nil
+		}
+		return nil, errNotFound
+	})
+}
+
+func remove(parent interface{}, key string) (interface{}, error) {
+	switch val := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := val[key]; !ok {
+			return nil, errNotFound
+		}
+		delete(val, key)
+		return val, nil
+	case []interface{}:
+		i, err := arrayIndex(key, len(val)-1)
+		if err != nil {
+			return nil, err
+		}
+		return append(val[:i], val[i+1:]...), nil
+	}
+
+	return nil, errNotFound
+}
+
+func replace(value interface{}) func(parent interface{}, key string) (interface{}, error) {
+	return func(parent interface{}, key string) (interface{}, error) {
+		switch val := parent.(type) {
+		case map[string]interface{}:
+			val[key] = value
+			return val, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(val)-1)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = value
+			return val, nil
+		}
+		return nil, errNotFound
+	}
+}
+
+// modify calls fn with the parent of the value at the path and the last token of the path, and replaces the parent by
+// the value fn returns, as changing the length of a list gives a new list.
+func modify(doc interface{}, path []string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+
+	switch val := doc.(type) {
+	case map[string]interface{}:
+		child, ok := val[path[0]]
+		if !ok {
+			return nil, errNotFound
+		}
+		changed, err := modify(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		val[path[0]] = changed
+		return val, nil
+	case []interface{}:
+		i, err := arrayIndex(path[0], len(val)-1)
+		if err != nil {
+			return nil, err
+		}
+		changed, err := modify(val[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		val[i] = changed
+		return val, nil
+	}
+
+	return nil, errNotFound
+}
+
+// arrayIndex parses the index of a list element, which must be between 0 and max.
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid index %s", token)
+	}
+	if i > max {
+		return 0, errNotFound
+	}
+
+	return i, nil
+}
+
+// equal reports whether two decoded values are equal, numbers being compared by value whether they are decoded from
+// JSON or YAML.
+func equal(a interface{}, b interface{}) bool {
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for k, v := range va {
+			if w, ok := vb[k]; !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if !equal(va[i], vb[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	ea, errA := docpath.Encode(docpath.Native(a))
+	eb, errB := docpath.Encode(docpath.Native(b))
+
+	return errA == nil && errB == nil && string(ea) == string(eb)
+}
+
+// deepCopy returns a copy of a decoded value, sharing nothing with it.
+func deepCopy(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			copied[k] = deepCopy(e)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(val))
+		for i, e := range val {
+			copied[i] = deepCopy(e)
+		}
+		return copied
+	}
+
+	return v
+}
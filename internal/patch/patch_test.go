@@ -0,0 +1,190 @@
+package patch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+const dagger = `{"name": "Dagger", "system": {"dmg": "1d4", "tags": ["light", "finesse"], "a/b": 1, "m~n": 2}, "flags": {}}`
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	v, err := docpath.Decode([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+func encode(v interface{}) string {
+	data, _ := json.Marshal(v)
+
+	return string(data)
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		patch    string
+		expected string
+		error    string
+	}{
+		{
+			patch:    `[{"op": "add", "path": "/system/range", "value": 20}]`,
+			expected: `{"flags":{},"name":"Dagger","system":{"a/b":1,"dmg":"1d4","m~n":2,"range":20,"tags":["light","finesse"]}}`,
+		},
+		{
+			patch:    `[{"op": "add", "path": "/system/tags/-", "value": "thrown"}, {"op": "add", "path": "/system/tags/0", "value": "simple"}]`,
+			expected: `{"flags":{},"name":"Dagger","system":{"a/b":1,"dmg":"1d4","m~n":2,"tags":["simple","light","finesse","thrown"]}}`,
+		},
+		{
+			patch:    `[{"op": "add", "path": "/system/tags/2", "value": "thrown"}]`,
+			expected: `{"flags":{},"name":"Dagger","system":{"a/b":1,"dmg":"1d4","m~n":2,"tags":["light","finesse","thrown"]}}`,
+		},
+		{
+			patch:    `[{"op": "add", "path": "", "value": {"name": "Sword"}}]`,
+			expected: `{"name":"Sword"}`,
+		},
+		{
+			patch:    `[{"op": "remove", "path": "/system/tags/0"}, {"op": "remove", "path": "/system/a~1b"}, {"op": "remove", "path": "/system/m~0n"}]`,
+			expected: `{"flags":{},"name":"Dagger","system":{"dmg":"1d4","tags":["finesse"]}}`,
+		},
+		{
+			patch:    `[{"op": "replace", "path": "/name", "value": "Knife"}, {"op": "replace", "path": "/system/tags/1", "value": "thrown"}]`,
+			expected: `{"flags":{},"name":"Knife","system":{"a/b":1,"dmg":"1d4","m~n":2,"tags":["light","thrown"]}}`,
+		},
+		{
+			patch:    `[{"op": "move", "from": "/system/dmg", "path": "/system/damage"}]`,
+			expected: `{"flags":{},"name":"Dagger","system":{"a/b":1,"damage":"1d4","m~n":2,"tags":["light","finesse"]}}`,
+		},
+		{
+			patch:    `[{"op": "move", "from": "/system/tags/1", "path": "/system/tags/0"}]`,
+			expected: `{"flags":{},"name":"Dagger","system":{"a/b":1,"dmg":"1d4","m~n":2,"tags":["finesse","light"]}}`,
+		},
+		{
+			patch:    `[{"op": "move", "from": "/name", "path": "/name"}]`,
+			expected: `{"flags":{},"name":"Dagger","system":{"a/b":1,"dmg":"1d4","m~n":2,"tags":["light","finesse"]}}`,
+		},
+		{
+			patch:    `[{"op": "copy", "from": "/system/tags", "path": "/flags/tags"}, {"op": "add", "path": "/flags/tags/-", "value": "x"}]`,
+			expected: `{"flags":{"tags":["light","finesse","x"]},"name":"Dagger","system":{"a/b":1,"dmg":"1d4","m~n":2,"tags":["light","finesse"]}}`,
+		},
+		{
+			patch:    `[{"op": "test", "path": "/system/a~1b", "value": 1.0}, {"op": "test", "path": "/system/tags", "value": ["light", "finesse"]}]`,
+			expected: `{"flags":{},"name":"Dagger","system":{"a/b":1,"dmg":"1d4","m~n":2,"tags":["light","finesse"]}}`,
+		},
+		{patch: `[{"op": "test", "path": "/name", "value": "Sword"}]`, error: "test /name: test failed"},
+		{patch: `[{"op": "test", "path": "/system/tags", "value": ["light"]}]`, error: "test /system/tags: test failed"},
+		{patch: `[{"op": "remove", "path": "/missing"}]`, error: "remove /missing: path not found"},
+		{patch: `[{"op": "remove", "path": ""}]`, error: "remove : cannot remove the document"},
+		{patch: `[{"op": "replace", "path": "/missing", "value": 1}]`, error: "replace /missing: path not found"},
+		{patch: `[{"op": "add", "path": "/missing/field", "value": 1}]`, error: "add /missing/field: path not found"},
+		{patch: `[{"op": "add", "path": "/system/tags/3", "value": 1}]`, error: "add /system/tags/3: path not found"},
+		{patch: `[{"op": "add", "path": "/system/tags/01", "value": 1}]`, error: "add /system/tags/01: invalid index 01"},
+		{patch: `[{"op": "remove", "path": "/system/tags/x"}]`, error: "remove /system/tags/x: invalid index x"},
+		{patch: `[{"op": "remove", "path": "/name/x"}]`, error: "remove /name/x: path not found"},
+		{patch: `[{"op": "move", "from": "/system", "path": "/system/old"}]`, error: "move /system to /system/old: cannot move a value into itself"},
+		{patch: `[{"op": "move", "from": "/missing", "path": "/name"}]`, error: "move /missing to /name: path not found"},
+		{patch: `[{"op": "copy", "from": "/missing", "path": "/name"}]`, error: "copy /missing to /name: path not found"},
+		{
+			patch: `[{"op": "replace", "path": "/name", "value": "Knife"}, {"op": "remove", "path": "/missing"}]`,
+			error: "remove /missing: path not found",
+		},
+	}
+
+	for _, tt := range tests {
+		doc := decode(t, dagger)
+		p, err := ParseJSONPatch(decode(t, tt.patch))
+		if err != nil {
+			t.Errorf("ParseJSONPatch(%s): %s", tt.patch, err)
+			continue
+		}
+		changed, err := p.Apply(doc)
+		if tt.error != "" {
+			if err == nil || err.Error() != tt.error {
+				t.Errorf("Apply(%s) = %v, expected %s", tt.patch, err, tt.error)
+			}
+		} else if err != nil {
+			t.Errorf("Apply(%s): %s", tt.patch, err)
+		} else if got := encode(changed); got != tt.expected {
+			t.Errorf("Apply(%s) = %s, expected %s", tt.patch, got, tt.expected)
+		}
+
+		if got := encode(doc); got != encode(decode(t, dagger)) {
+			t.Errorf("Apply(%s) changed the given document into %s", tt.patch, got)
+		}
+	}
+}
+
+func TestParseJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		patch string
+		error string
+	}{
+		{`{"op": "add"}`, "a JSON Patch is a list of operations"},
+		{`["add"]`, "operation 1 is not an object"},
+		{`[{"op": "remove", "path": "/a"}, {"op": "add", "value": 1}]`, "operation 2 has no path"},
+		{`[{"op": "add", "path": "/a"}]`, "operation 1 has no value"},
+		{`[{"op": "replace", "path": "/a"}]`, "operation 1 has no value"},
+		{`[{"op": "test", "path": "/a"}]`, "operation 1 has no value"},
+		{`[{"op": "move", "path": "/a"}]`, "operation 1 has no from"},
+		{`[{"op": "copy", "path": "/a"}]`, "operation 1 has no from"},
+		{`[{"op": "delete", "path": "/a"}]`, `operation 1 has an unknown op "delete"`},
+		{`[{"path": "/a"}]`, `operation 1 has an unknown op ""`},
+		{`[{"op": "remove", "path": "a"}]`, `operation 1: invalid path "a", it must start with /`},
+		{`[{"op": "move", "from": "a", "path": "/a"}]`, `operation 1: invalid path "a", it must start with /`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseJSONPatch(decode(t, tt.patch))
+		if err == nil || err.Error() != tt.error {
+			t.Errorf("ParseJSONPatch(%s) = %v, expected %s", tt.patch, err, tt.error)
+		}
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc      string
+		patch    string
+		expected string
+	}{
+		{`{"a": "b"}`, `{"a": "c"}`, `{"a":"c"}`},
+		{`{"a": "b"}`, `{"b": "c"}`, `{"a":"b","b":"c"}`},
+		{`{"a": "b"}`, `{"a": null}`, `{}`},
+		{`{"a": "b", "b": "c"}`, `{"a": null}`, `{"b":"c"}`},
+		{`{"a": ["b"]}`, `{"a": "c"}`, `{"a":"c"}`},
+		{`{"a": "c"}`, `{"a": ["b"]}`, `{"a":["b"]}`},
+		{`{"a": {"b": "c"}}`, `{"a": {"b": "d", "c": null}}`, `{"a":{"b":"d"}}`},
+		{`{"a": [{"b": "c"}]}`, `{"a": [1]}`, `{"a":[1]}`},
+		{`["a", "b"]`, `["c", "d"]`, `["c","d"]`},
+		{`{"a": "b"}`, `["c"]`, `["c"]`},
+		{`{"a": "foo"}`, `null`, `null`},
+		{`{"a": "foo"}`, `"bar"`, `"bar"`},
+		{`{"e": null}`, `{"a": 1}`, `{"a":1,"e":null}`},
+		{`[1, 2]`, `{"a": "b", "c": null}`, `{"a":"b"}`},
+		{`{}`, `{"a": {"bb": {"ccc": null}}}`, `{"a":{"bb":{}}}`},
+		{
+			`{"flags": {"old-module": {"rare": true}, "core": {"sheetClass": ""}}}`,
+			`{"flags": {"old-module": null, "new-module": {"rare": true}}}`,
+			`{"flags":{"core":{"sheetClass":""},"new-module":{"rare":true}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		doc := decode(t, tt.doc)
+		changed, err := MergePatch{Value: decode(t, tt.patch)}.Apply(doc)
+		if err != nil {
+			t.Errorf("Apply(%s, %s): %s", tt.doc, tt.patch, err)
+			continue
+		}
+		if got := encode(changed); got != tt.expected {
+			t.Errorf("Apply(%s, %s) = %s, expected %s", tt.doc, tt.patch, got, tt.expected)
+		}
+		if got := encode(doc); got != encode(decode(t, tt.doc)) {
+			t.Errorf("Apply(%s, %s) changed the given document into %s", tt.doc, tt.patch, got)
+		}
+	}
+}
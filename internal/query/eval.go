@@ -0,0 +1,489 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// node is a node of the syntax tree of an expression. It gives the values the expression outputs for an input value.
+type node interface {
+	eval(in interface{}, vars map[string]interface{}) ([]interface{}, error)
+}
+
+type identityNode struct{}
+
+func (identityNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	return []interface{}{in}, nil
+}
+
+type fieldNode struct {
+	name string
+}
+
+func (n fieldNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	switch val := in.(type) {
+	case map[string]interface{}:
+		return []interface{}{val[n.name]}, nil
+	case nil:
+		return []interface{}{nil}, nil
+	}
+
+	return nil, nil
+}
+
+type iterateNode struct{}
+
+func (iterateNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	switch val := in.(type) {
+	case []interface{}:
+		return val, nil
+	case map[string]interface{}:
+		var out []interface{}
+		for _, k := range sortedKeys(val) {
+			out = append(out, val[k])
+		}
+		return out, nil
+	}
+
+	return nil, nil
+}
+
+type indexNode struct {
+	target node
+	index  node
+}
+
+func (n indexNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	targets, err := n.target.eval(in, vars)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := n.index.eval(in, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	for _, t := range targets {
+		for _, i := range indexes {
+			switch val := t.(type) {
+			case map[string]interface{}:
+				if k, ok := i.(string); ok {
+					out = append(out, val[k])
+				}
+			case []interface{}:
+				if f, ok := number(i); ok {
+					idx := int(f)
+					if idx < 0 {
+						idx += len(val)
+					}
+					if idx >= 0 && idx < len(val) {
+						out = append(out, val[idx])
+					} else {
+						out = append(out, nil)
+					}
+				}
+			case nil:
+				out = append(out, nil)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+type pipeNode struct {
+	left  node
+	right node
+}
+
+func (n pipeNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	lefts, err := n.left.eval(in, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	for _, l := range lefts {
+		rights, err := n.right.eval(l, vars)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rights...)
+	}
+
+	return out, nil
+}
+
+type commaNode struct {
+	left  node
+	right node
+}
+
+func (n commaNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	lefts, err := n.left.eval(in, vars)
+	if err != nil {
+		return nil, err
+	}
+	rights, err := n.right.eval(in, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(lefts, rights...), nil
+}
+
+// alternativeNode gives the true values of its left expression, or the values of its right expression if there are
+// none, e.g. .name // "unnamed".
+type alternativeNode struct {
+	left  node
+	right node
+}
+
+func (n alternativeNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	lefts, err := n.left.eval(in, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	for _, l := range lefts {
+		if truthy(l) {
+			out = append(out, l)
+		}
+	}
+	if len(out) > 0 {
+		return out, nil
+	}
+
+	return n.right.eval(in, vars)
+}
+
+type logicalNode struct {
+	left  node
+	right node
+	and   bool
+}
+
+func (n logicalNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	lefts, err := n.left.eval(in, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	for _, l := range lefts {
+		// The right expression is only evaluated when the left one does not decide.
+		if truthy(l) != n.and {
+			out = append(out, !n.and)
+			continue
+		}
+		rights, err := n.right.eval(in, vars)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rights {
+			out = append(out, truthy(r))
+		}
+	}
+
+	return out, nil
+}
+
+type comparisonNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n comparisonNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	lefts, err := n.left.eval(in, vars)
+	if err != nil {
+		return nil, err
+	}
+	rights, err := n.right.eval(in, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	for _, l := range lefts {
+		for _, r := range rights {
+			c := compare(l, r)
+			switch n.op {
+			case "==":
+				out = append(out, c == 0)
+			case "!=":
+				out = append(out, c != 0)
+			case "<":
+				out = append(out, c < 0)
+			case "<=":
+				out = append(out, c <= 0)
+			case ">":
+				out = append(out, c > 0)
+			case ">=":
+				out = append(out, c >= 0)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	return []interface{}{n.value}, nil
+}
+
+// negateNode gives the opposite of the numbers of its operand, e.g. -.system.bonus.
+type negateNode struct {
+	operand node
+}
+
+func (n negateNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	values, err := n.operand.eval(in, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		f, ok := number(v)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %s", typeOf(v))
+		}
+		out[i] = json.Number(strconv.FormatFloat(-f, 'f', -1, 64))
+	}
+
+	return out, nil
+}
+
+type variableNode struct {
+	name string
+}
+
+func (n variableNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("$%s is not defined", n.name)
+	}
+
+	return []interface{}{v}, nil
+}
+
+// collectNode gives the list of the values of its expression, e.g. [.items[].name].
+type collectNode struct {
+	n node
+}
+
+func (n collectNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	list := []interface{}{}
+	if n.n != nil {
+		values, err := n.n.eval(in, vars)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, values...)
+	}
+
+	return []interface{}{list}, nil
+}
+
+type objectField struct {
+	key   node
+	value node
+}
+
+// objectNode builds objects, e.g. {name, ac: .system.attributes.ac.value}. A field having several values gives as many
+// objects.
+type objectNode struct {
+	fields []objectField
+}
+
+func (n objectNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	objects := []map[string]interface{}{{}}
+	for _, f := range n.fields {
+		keys, err := f.key.eval(in, vars)
+		if err != nil {
+			return nil, err
+		}
+		values, err := f.value.eval(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		var next []map[string]interface{}
+		for _, obj := range objects {
+			for _, k := range keys {
+				key, ok := k.(string)
+				if !ok {
+					return nil, fmt.Errorf("object keys must be strings, not %s", typeOf(k))
+				}
+				for _, v := range values {
+					copied := make(map[string]interface{}, len(obj)+1)
+					for ck, cv := range obj {
+						copied[ck] = cv
+					}
+					copied[key] = v
+					next = append(next, copied)
+				}
+			}
+		}
+		objects = next
+	}
+
+	out := make([]interface{}, len(objects))
+	for i, obj := range objects {
+		out[i] = obj
+	}
+
+	return out, nil
+}
+
+type callNode struct {
+	fn   function
+	args []node
+}
+
+func (n callNode) eval(in interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	return n.fn(in, n.args, vars)
+}
+
+// truthy reports whether a value is true: every value but false and null is.
+func truthy(v interface{}) bool {
+	return v != nil && v != false
+}
+
+// number returns the value of a number, whether it is decoded with json.Number or not.
+func number(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case json.Number:
+		f, err := val.Float64()
+		return f, err == nil
+	case float64:
+		return val, true
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	}
+
+	return 0, false
+}
+
+func typeOf(v interface{}) string {
+	if _, ok := number(v); ok {
+		return "number"
+	}
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
+// typeOrder orders the values of different types: null, false, true, numbers, strings, arrays then objects.
+func typeOrder(v interface{}) int {
+	switch typeOf(v) {
+	case "null":
+		return 0
+	case "boolean":
+		if v == true {
+			return 2
+		}
+		return 1
+	case "number":
+		return 3
+	case "string":
+		return 4
+	case "array":
+		return 5
+	}
+
+	return 6
+}
+
+// compare returns a negative number, zero or a positive number when a is lower than, equal to or greater than b.
+func compare(a interface{}, b interface{}) int {
+	ta, tb := typeOrder(a), typeOrder(b)
+	if ta != tb {
+		return ta - tb
+	}
+
+	switch va := a.(type) {
+	case string:
+		return compareOrdered(va, b.(string))
+	case []interface{}:
+		vb := b.([]interface{})
+		for i := 0; i < len(va) && i < len(vb); i++ {
+			if c := compare(va[i], vb[i]); c != 0 {
+				return c
+			}
+		}
+		return len(va) - len(vb)
+	case map[string]interface{}:
+		vb := b.(map[string]interface{})
+		ka, kb := sortedKeys(va), sortedKeys(vb)
+		if c := compare(stringList(ka), stringList(kb)); c != 0 {
+			return c
+		}
+		for _, k := range ka {
+			if c := compare(va[k], vb[k]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+
+	if fa, ok := number(a); ok {
+		fb, _ := number(b)
+		return compareOrdered(fa, fb)
+	}
+
+	return 0
+}
+
+func compareOrdered[T string | float64](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// stringList returns the list of the given strings, as decoded from JSON.
+func stringList(s []string) []interface{} {
+	list := make([]interface{}, len(s))
+	for i, v := range s {
+		list[i] = v
+	}
+
+	return list
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
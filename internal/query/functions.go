@@ -0,0 +1,283 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+// function is a builtin function, called with its input and its arguments, which are evaluated by the function itself
+// as some are conditions evaluated against other values, e.g. the elements of the input in any(.name == "Dagger").
+type function func(in interface{}, args []node, vars map[string]interface{}) ([]interface{}, error)
+
+type functionKey struct {
+	name  string
+	arity int
+}
+
+// functions are the builtin functions by name and number of arguments.
+var functions = map[functionKey]function{
+	{"not", 0}:            simple(func(in interface{}) (interface{}, bool) { return !truthy(in), true }),
+	{"length", 0}:         simple(length),
+	{"keys", 0}:           simple(keys),
+	{"type", 0}:           simple(func(in interface{}) (interface{}, bool) { return typeOf(in), true }),
+	{"tostring", 0}:       simple(toString),
+	{"ascii_downcase", 0}: simple(mapString(strings.ToLower)),
+	{"ascii_upcase", 0}:   simple(mapString(strings.ToUpper)),
+	{"empty", 0}:          empty,
+	{"any", 0}:            quantifier(true),
+	{"all", 0}:            quantifier(false),
+	{"any", 1}:            quantifier(true),
+	{"all", 1}:            quantifier(false),
+	{"select", 1}:         selectFn,
+	{"map", 1}:            mapFn,
+	{"contains", 1}:       withArgument(func(in interface{}, arg interface{}) (interface{}, error) { return contains(in, arg), nil }),
+	{"has", 1}:            withArgument(has),
+	{"startswith", 1}:     withArgument(stringTest(strings.HasPrefix)),
+	{"endswith", 1}:       withArgument(stringTest(strings.HasSuffix)),
+	{"test", 1}:           withArgument(test),
+}
+
+// simple returns a function without argument giving one value, or none if the input has the wrong type.
+func simple(fn func(in interface{}) (interface{}, bool)) function {
+	return func(in interface{}, args []node, vars map[string]interface{}) ([]interface{}, error) {
+		if v, ok := fn(in); ok {
+			return []interface{}{v}, nil
+		}
+		return nil, nil
+	}
+}
+
+// withArgument returns a function with one argument, evaluated against the input, giving one value for each value of
+// the argument.
+func withArgument(fn func(in interface{}, arg interface{}) (interface{}, error)) function {
+	return func(in interface{}, args []node, vars map[string]interface{}) ([]interface{}, error) {
+		values, err := args[0].eval(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		var out []interface{}
+		for _, arg := range values {
+			v, err := fn(in, arg)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	}
+}
+
+// selectFn gives its input if its condition gives a true value, e.g. select(.type == "weapon"). Unlike jq, it gives it
+// once even when the condition gives several true values, e.g. select(.items[].type == "weapon").
+func selectFn(in interface{}, args []node, vars map[string]interface{}) ([]interface{}, error) {
+	values, err := args[0].eval(in, vars)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		if truthy(v) {
+			return []interface{}{in}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func mapFn(in interface{}, args []node, vars map[string]interface{}) ([]interface{}, error) {
+	list, ok := in.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	mapped := []interface{}{}
+	for _, e := range list {
+		values, err := args[0].eval(e, vars)
+		if err != nil {
+			return nil, err
+		}
+		mapped = append(mapped, values...)
+	}
+
+	return []interface{}{mapped}, nil
+}
+
+func empty(in interface{}, args []node, vars map[string]interface{}) ([]interface{}, error) {
+	return nil, nil
+}
+
+// quantifier returns the any or all function, testing the elements of the input list, or the values its condition
+// argument gives for them.
+func quantifier(anyOf bool) function {
+	return func(in interface{}, args []node, vars map[string]interface{}) ([]interface{}, error) {
+		list, ok := in.([]interface{})
+		if !ok {
+			return nil, nil
+		}
+
+		for _, e := range list {
+			values := []interface{}{e}
+			if len(args) > 0 {
+				var err error
+				if values, err = args[0].eval(e, vars); err != nil {
+					return nil, err
+				}
+			}
+			for _, v := range values {
+				if truthy(v) == anyOf {
+					return []interface{}{anyOf}, nil
+				}
+			}
+		}
+
+		return []interface{}{!anyOf}, nil
+	}
+}
+
+func length(in interface{}) (interface{}, bool) {
+	switch val := in.(type) {
+	case nil:
+		return json.Number("0"), true
+	case string:
+		return json.Number(strconv.Itoa(utf8.RuneCountInString(val))), true
+	case []interface{}:
+		return json.Number(strconv.Itoa(len(val))), true
+	case map[string]interface{}:
+		return json.Number(strconv.Itoa(len(val))), true
+	}
+
+	return nil, false
+}
+
+func keys(in interface{}) (interface{}, bool) {
+	switch val := in.(type) {
+	case []interface{}:
+		indexes := make([]interface{}, len(val))
+		for i := range val {
+			indexes[i] = json.Number(strconv.Itoa(i))
+		}
+		return indexes, true
+	case map[string]interface{}:
+		return stringList(sortedKeys(val)), true
+	}
+
+	return nil, false
+}
+
+func toString(in interface{}) (interface{}, bool) {
+	if s, ok := in.(string); ok {
+		return s, true
+	}
+	data, err := docpath.Encode(in)
+
+	return string(data), err == nil
+}
+
+func mapString(fn func(string) string) func(in interface{}) (interface{}, bool) {
+	return func(in interface{}) (interface{}, bool) {
+		s, ok := in.(string)
+		if !ok {
+			return nil, false
+		}
+		return fn(s), true
+	}
+}
+
+func stringTest(fn func(s string, arg string) bool) func(in interface{}, arg interface{}) (interface{}, error) {
+	return func(in interface{}, arg interface{}) (interface{}, error) {
+		s, ok := in.(string)
+		a, argOk := arg.(string)
+		return ok && argOk && fn(s, a), nil
+	}
+}
+
+// regexps caches the regular expressions of test, which is called for each document, possibly from several goroutines.
+var regexps = struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}{compiled: map[string]*regexp.Regexp{}}
+
+func test(in interface{}, arg interface{}) (interface{}, error) {
+	pattern, ok := arg.(string)
+	if !ok {
+		return nil, fmt.Errorf("test needs a regular expression, not %s", typeOf(arg))
+	}
+	regexps.Lock()
+	re, ok := regexps.compiled[pattern]
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			regexps.Unlock()
+			return nil, fmt.Errorf("invalid regular expression %s: %s", pattern, err)
+		}
+		regexps.compiled[pattern] = re
+	}
+	regexps.Unlock()
+
+	s, ok := in.(string)
+
+	return ok && re.MatchString(s), nil
+}
+
+func has(in interface{}, arg interface{}) (interface{}, error) {
+	switch val := in.(type) {
+	case map[string]interface{}:
+		k, ok := arg.(string)
+		_, exists := val[k]
+		return ok && exists, nil
+	case []interface{}:
+		i, ok := number(arg)
+		return ok && i >= 0 && int(i) < len(val), nil
+	}
+
+	return false, nil
+}
+
+// contains reports whether a contains b: b is a substring of a, every element of b is contained by an element of a, or
+// every field of b is contained by the same field of a. Other values must be equal.
+func contains(a interface{}, b interface{}) bool {
+	switch va := a.(type) {
+	case string:
+		vb, ok := b.(string)
+		return ok && strings.Contains(va, vb)
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, eb := range vb {
+			found := false
+			for _, ea := range va {
+				if contains(ea, eb) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, eb := range vb {
+			ea, exists := va[k]
+			if !exists || !contains(ea, eb) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return compare(a, b) == 0
+}
@@ -0,0 +1,111 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenIdent
+	tokenVariable
+	tokenString
+	tokenNumber
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value string
+	pos   int
+}
+
+// punctuations are the operators and delimiters of the language, longest first.
+var punctuations = []string{"==", "!=", "<=", ">=", "//", "|", ",", ".", "(", ")", "[", "]", "{", "}", ":", ";", "<", ">", "?", "-"}
+
+// tokenize splits an expression into tokens, ending with an EOF token.
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			end, value, err := readString(expr, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: expr[i:end], value: value, pos: i})
+			i = end
+		case c >= '0' && c <= '9':
+			end := i
+			for end < len(expr) && (expr[end] >= '0' && expr[end] <= '9' || expr[end] == '.' || expr[end] == 'e' || expr[end] == 'E') {
+				end++
+			}
+			if _, err := strconv.ParseFloat(expr[i:end], 64); err != nil {
+				return nil, fmt.Errorf("invalid number %s at %d", expr[i:end], i+1)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[i:end], value: expr[i:end], pos: i})
+			i = end
+		case c == '$' || isIdentStart(c):
+			kind := tokenIdent
+			start := i
+			if c == '$' {
+				kind = tokenVariable
+				start++
+			}
+			end := start
+			for end < len(expr) && (isIdentStart(rune(expr[end])) || expr[end] >= '0' && expr[end] <= '9') {
+				end++
+			}
+			if end == start {
+				return nil, fmt.Errorf("missing variable name at %d", i+1)
+			}
+			tokens = append(tokens, token{kind: kind, text: expr[i:end], value: expr[start:end], pos: i})
+			i = end
+		default:
+			found := false
+			for _, p := range punctuations {
+				if strings.HasPrefix(expr[i:], p) {
+					tokens = append(tokens, token{kind: tokenPunct, text: p, pos: i})
+					i += len(p)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected character %q at %d", c, i+1)
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(expr)}), nil
+}
+
+// readString reads the JSON string starting at start. It returns the position following it and its value.
+func readString(expr string, start int) (int, string, error) {
+	for i := start + 1; i < len(expr); i++ {
+		switch expr[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err := strconv.Unquote(expr[start : i+1])
+			if err != nil {
+				return 0, "", fmt.Errorf("invalid string %s at %d", expr[start:i+1], start+1)
+			}
+			return i + 1, value, nil
+		}
+	}
+
+	return 0, "", fmt.Errorf("unterminated string at %d", start+1)
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,365 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// parser builds the syntax tree of an expression, from the lowest precedence to the highest:
+// pipe (|), comma (,), alternative (//), or, and, comparisons, then the terms and their suffixes.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) is(kind tokenKind, text string) bool {
+	t := p.peek()
+
+	return t.kind == kind && t.text == text
+}
+
+func (p *parser) accept(text string) bool {
+	if p.is(tokenPunct, text) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected()
+	}
+
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+
+	return fmt.Errorf("unexpected %s at %d", t.text, t.pos+1)
+}
+
+func (p *parser) parsePipe() (node, error) {
+	left, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("|") {
+		return left, nil
+	}
+	right, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+
+	return pipeNode{left, right}, nil
+}
+
+func (p *parser) parseComma() (node, error) {
+	left, err := p.parseAlternative()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(",") {
+		right, err := p.parseAlternative()
+		if err != nil {
+			return nil, err
+		}
+		left = commaNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAlternative() (node, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("//") {
+		right, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		left = alternativeNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tokenIdent, "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{left: left, right: right, and: false}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tokenIdent, "and") {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{left: left, right: right, and: true}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.parsePostfix()
+			if err != nil {
+				return nil, err
+			}
+			return comparisonNode{op: op, left: left, right: right}, nil
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.is(tokenPunct, "."):
+			p.next()
+			if p.is(tokenPunct, "[") {
+				continue
+			}
+			if !p.followsDot() {
+				return nil, p.unexpected()
+			}
+			field, err := p.parseFieldName()
+			if err != nil {
+				return nil, err
+			}
+			n = pipeNode{n, fieldNode{field}}
+		case p.accept("["):
+			if p.accept("]") {
+				n = pipeNode{n, iterateNode{}}
+				continue
+			}
+			index, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = indexNode{target: n, index: index}
+		case p.accept("?"):
+			// Errors are already ignored: a missing field gives null and iterating over a scalar gives nothing.
+		default:
+			return n, nil
+		}
+	}
+}
+
+// followsDot reports whether the next token is a field name written right after the previous dot, e.g. in .name.
+func (p *parser) followsDot() bool {
+	t := p.peek()
+
+	return (t.kind == tokenIdent || t.kind == tokenString) && t.pos == p.tokens[p.pos-1].pos+1
+}
+
+func (p *parser) parseFieldName() (string, error) {
+	t := p.peek()
+	if t.kind != tokenIdent && t.kind != tokenString {
+		return "", p.unexpected()
+	}
+	p.next()
+
+	return t.value, nil
+}
+
+func (p *parser) parseTerm() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenNumber:
+		p.next()
+		return literalNode{json.Number(t.value)}, nil
+	case tokenString:
+		p.next()
+		return literalNode{t.value}, nil
+	case tokenVariable:
+		p.next()
+		return variableNode{t.value}, nil
+	case tokenIdent:
+		p.next()
+		switch t.value {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		case "null":
+			return literalNode{nil}, nil
+		}
+		return p.parseCall(t)
+	}
+
+	switch {
+	case p.accept("."):
+		if p.followsDot() {
+			field, err := p.parseFieldName()
+			if err != nil {
+				return nil, err
+			}
+			return fieldNode{field}, nil
+		}
+		return identityNode{}, nil
+	case p.accept("-"):
+		operand, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		if l, ok := operand.(literalNode); ok {
+			if n, ok := l.value.(json.Number); ok && !strings.HasPrefix(string(n), "-") {
+				return literalNode{json.Number("-" + string(n))}, nil
+			}
+		}
+		return negateNode{operand}, nil
+	case p.accept("("):
+		n, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.expect(")")
+	case p.accept("["):
+		if p.accept("]") {
+			return collectNode{}, nil
+		}
+		n, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return collectNode{n}, p.expect("]")
+	case p.accept("{"):
+		return p.parseObject()
+	}
+
+	return nil, p.unexpected()
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	var args []node
+	if p.accept("(") {
+		for {
+			arg, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	f, ok := functions[functionKey{name.value, len(args)}]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s/%d at %d", name.value, len(args), name.pos+1)
+	}
+
+	return callNode{fn: f, args: args}, nil
+}
+
+func (p *parser) parseObject() (node, error) {
+	var fields []objectField
+	for !p.accept("}") {
+		if len(fields) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		t := p.peek()
+		if t.kind == tokenEOF {
+			return nil, p.unexpected()
+		}
+		p.next()
+		var field objectField
+		switch t.kind {
+		case tokenIdent, tokenString:
+			field.key = literalNode{t.value}
+			field.value = fieldNode{t.value}
+		case tokenVariable:
+			field.key = literalNode{t.value}
+			field.value = variableNode{t.value}
+		default:
+			if t.kind != tokenPunct || t.text != "(" {
+				p.pos--
+				return nil, p.unexpected()
+			}
+			key, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			field.key = key
+			field.value = nil
+		}
+
+		if p.accept(":") {
+			value, err := p.parseAlternative()
+			if err != nil {
+				return nil, err
+			}
+			field.value = value
+		} else if field.value == nil {
+			return nil, p.unexpected()
+		}
+		fields = append(fields, field)
+	}
+
+	return objectNode{fields}, nil
+}
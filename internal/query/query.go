@@ -0,0 +1,66 @@
+package query
+
+// Query is a compiled expression of a subset of the jq language (https://jqlang.github.io/jq/manual/), evaluated
+// against the documents: paths (.system.price.value, .items[], .effects[0]), pipes (|), negations (-1,
+// -.system.bonus), comparisons, and, or, alternatives (//), lists ([...]), objects ({name, ac:
+// .system.attributes.ac.value}), variables ($pack) and the not, length, keys, type, tostring, ascii_downcase,
+// ascii_upcase, empty, any, all, select, map, contains, has, startswith, endswith and test functions.
+//
+// Evaluating a path against a value of the wrong type gives nothing instead of an error, so that an expression can be
+// evaluated against documents of any type.
+type Query struct {
+	root node
+}
+
+// Parse compiles an expression.
+func Parse(expr string) (*Query, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.unexpected()
+	}
+
+	return &Query{root: root}, nil
+}
+
+// Run evaluates the query against a value decoded from JSON, the variables being given without their $. It returns
+// every value the query gives.
+func (q *Query) Run(v interface{}, vars map[string]interface{}) ([]interface{}, error) {
+	return q.root.eval(v, vars)
+}
+
+// Columns returns the keys of the objects built by the query when it ends with an object whose keys are not computed,
+// e.g. name and ac for select(.type == "npc") | {name, ac: .system.attributes.ac.value}, in their order.
+func (q *Query) Columns() []string {
+	last := q.root
+	for {
+		pipe, ok := last.(pipeNode)
+		if !ok {
+			break
+		}
+		last = pipe.right
+	}
+
+	obj, ok := last.(objectNode)
+	if !ok {
+		return nil
+	}
+	var columns []string
+	for _, f := range obj.fields {
+		key, ok := f.key.(literalNode)
+		if !ok {
+			return nil
+		}
+		columns = append(columns, key.value.(string))
+	}
+
+	return columns
+}
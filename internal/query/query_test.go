@@ -0,0 +1,253 @@
+package query
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+const goblin = `{
+	"name": "Goblin",
+	"type": "npc",
+	"system": {"attributes": {"ac": {"value": 15}, "hp": {"value": -2}}, "bonus": 3, "tags": ["small", "goblinoid"]},
+	"items": [
+		{"name": "Scimitar", "type": "weapon", "system": {"price": 25}},
+		{"name": "Shortbow", "type": "weapon", "system": {"price": 25}},
+		{"name": "Leather", "type": "armor", "system": {"price": 10}}
+	],
+	"folder": null
+}`
+
+func TestRun(t *testing.T) {
+	doc, err := docpath.Decode([]byte(goblin))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		// Paths.
+		{".", ""},
+		{".name", `["Goblin"]`},
+		{".system.attributes.ac.value", `[15]`},
+		{`."name"`, `["Goblin"]`},
+		{`.["type"]`, `["npc"]`},
+		{".missing.field", `[null]`},
+		{".name.first", `[]`},
+		{".items[].name", `["Scimitar","Shortbow","Leather"]`},
+		{".items[0].name", `["Scimitar"]`},
+		{".items[-1].name", `["Leather"]`},
+		{".items[5]", `[null]`},
+		{".system.tags[]?", `["small","goblinoid"]`},
+		{".name[]", `[]`},
+		// Pipes, commas and alternatives.
+		{".items[] | .system.price", `[25,25,10]`},
+		{".name, .type", `["Goblin","npc"]`},
+		{".folder // \"none\"", `["none"]`},
+		{".name // \"none\"", `["Goblin"]`},
+		// Comparisons and logic.
+		{".system.bonus == 3", `[true]`},
+		{".system.bonus != 3", `[false]`},
+		{".system.bonus < 4", `[true]`},
+		{".system.bonus <= 2", `[false]`},
+		{".system.bonus > 2.5", `[true]`},
+		{".system.bonus >= 3", `[true]`},
+		{`.name < "Hobgoblin"`, `[true]`},
+		{"null < false", `[true]`},
+		{`1 < "1"`, `[true]`},
+		{".type == \"npc\" and .system.bonus > 5", `[false]`},
+		{".type == \"pc\" or .system.bonus > 1", `[true]`},
+		{"false and $undefined", `[false]`},
+		// Negative numbers.
+		{".system.attributes.hp.value < -1", `[true]`},
+		{".system.attributes.hp.value == -2", `[true]`},
+		{"-.system.bonus", `[-3]`},
+		{"-(.items[].system.price)", `[-25,-25,-10]`},
+		{"--1", `[1]`},
+		{"[.items[] | select(.system.price > -1) | .name] | length", `[3]`},
+		// Lists, objects and variables.
+		{"[.items[].type]", `[["weapon","weapon","armor"]]`},
+		{"[]", `[[]]`},
+		{"{name, ac: .system.attributes.ac.value}", `[{"ac":15,"name":"Goblin"}]`},
+		{`{"n": .name, $pack}`, `[{"n":"Goblin","pack":"monsters"}]`},
+		{"{(.type): .name}", `[{"npc":"Goblin"}]`},
+		{"{name: .items[].name}", `[{"name":"Scimitar"},{"name":"Shortbow"},{"name":"Leather"}]`},
+		{"$pack", `["monsters"]`},
+		// Functions.
+		{".items[] | select(.type == \"armor\") | .name", `["Leather"]`},
+		{"select(.items[].type == \"weapon\") | .name", `["Goblin"]`},
+		{".items | map(.system.price)", `[[25,25,10]]`},
+		{".items | any(.type == \"armor\")", `[true]`},
+		{".items | all(.type == \"weapon\")", `[false]`},
+		{"[true, false] | any", `[true]`},
+		{"[true, false] | all", `[false]`},
+		{".folder | not", `[true]`},
+		{".items | length", `[3]`},
+		{".name | length", `[6]`},
+		{".folder | length", `[0]`},
+		{".system | keys", `[["attributes","bonus","tags"]]`},
+		{".system.tags | keys", `[[0,1]]`},
+		{"[.name, .system.bonus, .folder, .items, .system, true] | map(type)", `[["string","number","null","array","object","boolean"]]`},
+		{".system.bonus | tostring", `["3"]`},
+		{".name | ascii_downcase", `["goblin"]`},
+		{".name | ascii_upcase", `["GOBLIN"]`},
+		{"empty", `[]`},
+		{".system.tags | contains([\"small\"])", `[true]`},
+		{`.system | contains({"tags": ["goblinoid"]})`, `[true]`},
+		{`.name | contains("obl")`, `[true]`},
+		{`has("folder")`, `[true]`},
+		{`has("img")`, `[false]`},
+		{".items | has(2)", `[true]`},
+		{`.name | startswith("Gob")`, `[true]`},
+		{`.name | endswith("Gob")`, `[false]`},
+		{`.name | test("^g.b", "")`, ""},
+		{`.name | test("^G.b")`, `[true]`},
+		{`.items[] | select(.name | test("^S")) | .name`, `["Scimitar","Shortbow"]`},
+	}
+
+	vars := map[string]interface{}{"pack": "monsters"}
+	for _, tt := range tests {
+		if tt.expected == "" {
+			continue
+		}
+		q, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%s): %s", tt.expr, err)
+			continue
+		}
+		values, err := q.Run(doc, vars)
+		if err != nil {
+			t.Errorf("Run(%s): %s", tt.expr, err)
+			continue
+		}
+		if values == nil {
+			values = []interface{}{}
+		}
+		got, _ := json.Marshal(values)
+		if string(got) != tt.expected {
+			t.Errorf("Run(%s) = %s, expected %s", tt.expr, got, tt.expected)
+		}
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	q, err := Parse(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := q.Run("x", nil)
+	if err != nil || len(values) != 1 || values[0] != "x" {
+		t.Errorf("Run(.) = %v, %v, expected [x]", values, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr  string
+		error string
+	}{
+		{"", "unexpected end of expression"},
+		{".name |", "unexpected end of expression"},
+		{".items[", "unexpected end of expression"},
+		{"(.name", "unexpected end of expression"},
+		{".name )", "unexpected ) at 7"},
+		{". name", "unexpected name at 3"},
+		{"{name:}", "unexpected } at 7"},
+		{"{1}", "unexpected 1 at 2"},
+		{"{(.name)}", "unexpected } at 9"},
+		{"unknown", "unknown function unknown/0 at 1"},
+		{"select(.a; .b)", "unknown function select/2 at 1"},
+		{`"unterminated`, "unterminated string at 1"},
+		{`"\q"`, `invalid string "\q" at 1`},
+		{"1.2.3", "invalid number 1.2.3 at 1"},
+		{"$", "missing variable name at 1"},
+		{".a = 1", "unexpected character '=' at 4"},
+		{"-", "unexpected end of expression"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		if err == nil {
+			t.Errorf("Parse(%s) succeeded, expected %s", tt.expr, tt.error)
+			continue
+		}
+		if err.Error() != tt.error {
+			t.Errorf("Parse(%s) = %s, expected %s", tt.expr, err, tt.error)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	doc, _ := docpath.Decode([]byte(goblin))
+	tests := []struct {
+		expr  string
+		error string
+	}{
+		{"$missing", "$missing is not defined"},
+		{"-.name", "cannot negate string"},
+		{"{(.system.bonus): 1}", "object keys must be strings, not number"},
+		{`.name | test("(")`, "invalid regular expression ("},
+		{".name | test(1)", "test needs a regular expression, not number"},
+	}
+
+	for _, tt := range tests {
+		q, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%s): %s", tt.expr, err)
+			continue
+		}
+		_, err = q.Run(doc, nil)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.error) {
+			t.Errorf("Run(%s) = %v, expected %s", tt.expr, err, tt.error)
+		}
+	}
+}
+
+func TestColumns(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected []string
+	}{
+		{"{name, ac: .system.attributes.ac.value}", []string{"name", "ac"}},
+		{`select(.type == "npc") | {type, "name"}`, []string{"type", "name"}},
+		{"{(.type): .name}", nil},
+		{".name", nil},
+	}
+
+	for _, tt := range tests {
+		q, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := q.Columns(); !slices.Equal(got, tt.expected) {
+			t.Errorf("Columns(%s) = %v, expected %v", tt.expr, got, tt.expected)
+		}
+	}
+}
+
+func TestConcurrentTest(t *testing.T) {
+	q, err := Parse(`.name | test("^G")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := q.Run(map[string]interface{}{"name": "Goblin"}, nil); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,160 @@
+package release
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BumpVersion increments the major, minor or patch part of a semantic version like "1.2.3".
+func BumpVersion(version string, part string) (string, error) {
+	prefix := ""
+	if strings.HasPrefix(version, "v") {
+		prefix = "v"
+	}
+
+	parts := strings.Split(strings.TrimPrefix(version, prefix), ".")
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+
+	numbers := make([]int, 3)
+	for i := range numbers {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return "", fmt.Errorf("cannot bump version \"%s\": it is not a semantic version\n", version)
+		}
+		numbers[i] = n
+	}
+
+	switch part {
+	case "major":
+		numbers = []int{numbers[0] + 1, 0, 0}
+	case "minor":
+		numbers = []int{numbers[0], numbers[1] + 1, 0}
+	case "patch":
+		numbers[2]++
+	default:
+		return "", fmt.Errorf("unknown version part \"%s\"\n", part)
+	}
+
+	return fmt.Sprintf("%s%d.%d.%d", prefix, numbers[0], numbers[1], numbers[2]), nil
+}
+
+// ExpandURL replaces the {id} and {version} placeholders of an URL template.
+func ExpandURL(template string, id string, version string) string {
+	return strings.NewReplacer("{id}", id, "{version}", version).Replace(template)
+}
+
+// dbRuntimeFiles are the files of a LevelDB which are only useful to a running Foundry.
+var dbRuntimeFiles = map[string]bool{"LOCK": true, "LOG": true, "LOG.old": true}
+
+// Zip writes the archive of a package: every file of the root directory, except the ones matching one of the exclude
+// patterns and the runtime files of the LevelDB directories given relative to the root.
+func Zip(root string, output string, exclude []string, databases []string) error {
+	absOutput, err := filepath.Abs(output)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("cannot create archive: %s\n", err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		if excluded(rel, exclude) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if abs, _ := filepath.Abs(p); abs == absOutput {
+			return nil
+		}
+		if dbRuntimeFiles[d.Name()] && isInside(filepath.Dir(rel), databases) {
+			return nil
+		}
+
+		return addFile(w, p, filepath.ToSlash(rel))
+	})
+	if err != nil {
+		w.Close()
+		return fmt.Errorf("cannot build archive: %s\n", err)
+	}
+
+	return w.Close()
+}
+
+// excluded reports whether the relative path or its base name matches one of the patterns.
+func excluded(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, filepath.ToSlash(rel)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(rel)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isInside(dir string, databases []string) bool {
+	for _, db := range databases {
+		if filepath.Clean(db) == dir {
+			return true
+		}
+	}
+
+	return false
+}
+
+func addFile(w *zip.Writer, p string, name string) error {
+	src, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	dst, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+
+	return err
+}
@@ -0,0 +1,60 @@
+package rename
+
+import (
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+// Module replaces every reference to the old module id inside the decoded document v: asset paths, compendium UUIDs,
+// including the ones written in free text or macros, flags namespaces and flags keys written in strings such as active
+// effect changes. It returns the number of replacements.
+func Module(v interface{}, old string, new string) int {
+	count := 0
+
+	docpath.EachObject(v, func(path string, obj map[string]interface{}) {
+		flags, ok := obj["flags"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		scope, ok := flags[old]
+		if !ok {
+			return
+		}
+
+		delete(flags, old)
+		if existing, ok := flags[new].(map[string]interface{}); ok {
+			if m, ok := scope.(map[string]interface{}); ok {
+				for k, v := range m {
+					existing[k] = v
+				}
+				scope = existing
+			}
+		}
+		flags[new] = scope
+		count++
+	})
+
+	docpath.MapStrings(v, func(path string, s string) string {
+		// Compendium UUIDs are replaced as plain text, so that the ones outside enrichers, e.g. in macros calling
+		// fromUuid, are replaced too.
+		ns := s
+		for _, r := range []struct{ old, new string }{
+			{"Compendium." + old + ".", "Compendium." + new + "."},
+			{"@Compendium[" + old + ".", "@Compendium[" + new + "."},
+			{"modules/" + old + "/", "modules/" + new + "/"},
+			{"flags." + old + ".", "flags." + new + "."},
+		} {
+			count += strings.Count(ns, r.old)
+			ns = strings.ReplaceAll(ns, r.old, r.new)
+		}
+		if ns == "flags."+old {
+			ns = "flags." + new
+			count++
+		}
+
+		return ns
+	})
+
+	return count
+}
@@ -0,0 +1,74 @@
+package rename
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+func TestModule(t *testing.T) {
+	tests := []struct {
+		doc      string
+		expected string
+		count    int
+	}{
+		{
+			`{"text": "See @UUID[Compendium.old-mod.items.Item.aaaaaaaaaaaaaaaa]{Sword}."}`,
+			`{"text":"See @UUID[Compendium.new-mod.items.Item.aaaaaaaaaaaaaaaa]{Sword}."}`,
+			1,
+		},
+		{
+			`{"text": "@Compendium[old-mod.items.Sword]{Sword} and @Compendium[other.items.Axe]"}`,
+			`{"text":"@Compendium[new-mod.items.Sword]{Sword} and @Compendium[other.items.Axe]"}`,
+			1,
+		},
+		{
+			`{"_stats": {"compendiumSource": "Compendium.old-mod.items.Item.aaaaaaaaaaaaaaaa"}}`,
+			`{"_stats":{"compendiumSource":"Compendium.new-mod.items.Item.aaaaaaaaaaaaaaaa"}}`,
+			1,
+		},
+		{
+			`{"command": "const a = await fromUuid('Compendium.old-mod.items.Item.aaaaaaaaaaaaaaaa');\nconst b = await fromUuid('Compendium.old-mod.spells.Item.bbbbbbbbbbbbbbbb');"}`,
+			`{"command":"const a = await fromUuid('Compendium.new-mod.items.Item.aaaaaaaaaaaaaaaa');\nconst b = await fromUuid('Compendium.new-mod.spells.Item.bbbbbbbbbbbbbbbb');"}`,
+			2,
+		},
+		{
+			`{"img": "modules/old-mod/icons/sword.webp", "other": "modules/old-mod-extra/icon.webp"}`,
+			`{"img":"modules/new-mod/icons/sword.webp","other":"modules/old-mod-extra/icon.webp"}`,
+			1,
+		},
+		{
+			`{"flags": {"old-mod": {"rare": true}, "core": {}}}`,
+			`{"flags":{"core":{},"new-mod":{"rare":true}}}`,
+			1,
+		},
+		{
+			`{"flags": {"old-mod": {"rare": true}, "new-mod": {"magic": true}}}`,
+			`{"flags":{"new-mod":{"magic":true,"rare":true}}}`,
+			1,
+		},
+		{
+			`{"changes": [{"key": "flags.old-mod.bonus", "value": "1"}, {"key": "flags.old-mod"}]}`,
+			`{"changes":[{"key":"flags.new-mod.bonus","value":"1"},{"key":"flags.new-mod"}]}`,
+			2,
+		},
+		{
+			`{"text": "Compendium.other.items.Item.aaaaaaaaaaaaaaaa, old-mod"}`,
+			`{"text":"Compendium.other.items.Item.aaaaaaaaaaaaaaaa, old-mod"}`,
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		v, err := docpath.Decode([]byte(tt.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := Module(v, "old-mod", "new-mod")
+		got, _ := json.Marshal(v)
+		if string(got) != tt.expected || count != tt.count {
+			t.Errorf("Module(%s) = %s, %d, expected %s, %d", tt.doc, got, count, tt.expected, tt.count)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package richtext
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/JohannesKaufmann/html-to-markdown/plugin"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// MarkdownExtension is the extension of the files holding the rich-text fields converted to Markdown.
+const MarkdownExtension = ".md"
+
+// enrichers match the Foundry enrichers, as written in Markdown: document links like @UUID[...]{label}, inline rolls
+// like [[/r 1d20]] and system enrichers like &Reference[prone]. In HTML, the & of the latter is escaped.
+var (
+	markdownEnrichers = regexp.MustCompile(`\[\[.*?\]\]|[@&]\w+\[[^\]]*\](\{[^}]*\})?`)
+	htmlEnrichers     = regexp.MustCompile(`\[\[.*?\]\]|(@|&amp;)\w+\[[^\]]*\](\{[^}]*\})?`)
+	placeholders      = regexp.MustCompile(`FVTTENRICHER(\d+)X`)
+)
+
+// htmlEscaper escapes the text of an enricher to be written in HTML, leaving the quotes as Foundry does.
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// keptTags are the tags kept as HTML in Markdown, as Markdown has no equivalent, e.g. the secrets of the journals.
+var keptTags = []string{"section", "aside", "figure", "iframe", "video", "audio"}
+
+// ToMarkdown converts HTML to Markdown, keeping the enrichers verbatim.
+func ToMarkdown(text string) (string, error) {
+	protected, enrichers := protect(text, htmlEnrichers)
+
+	conv := md.NewConverter("", true, nil)
+	conv.Use(plugin.GitHubFlavored())
+	conv.Keep(keptTags...)
+	markdown, err := conv.ConvertString(protected)
+	if err != nil {
+		return "", err
+	}
+
+	return restore(markdown, enrichers, html.UnescapeString), nil
+}
+
+// ToHTML converts Markdown to HTML, keeping the enrichers verbatim.
+func ToHTML(markdown string) (string, error) {
+	protected, enrichers := protect(markdown, markdownEnrichers)
+
+	var buf bytes.Buffer
+	gm := goldmark.New(goldmark.WithExtensions(extension.GFM), goldmark.WithRendererOptions(gmhtml.WithUnsafe()))
+	if err := gm.Convert([]byte(protected), &buf); err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(restore(buf.String(), enrichers, htmlEscaper.Replace), "\n"), nil
+}
+
+// protect replaces the enrichers of the text by placeholders which the conversions leave untouched.
+func protect(text string, enrichers *regexp.Regexp) (string, []string) {
+	var found []string
+	protected := enrichers.ReplaceAllStringFunc(text, func(e string) string {
+		found = append(found, e)
+		return fmt.Sprintf("FVTTENRICHER%dX", len(found)-1)
+	})
+
+	return protected, found
+}
+
+// restore replaces the placeholders of the text by the enrichers, converted by fn.
+func restore(text string, enrichers []string, fn func(string) string) string {
+	return placeholders.ReplaceAllStringFunc(text, func(p string) string {
+		i, err := strconv.Atoi(placeholders.FindStringSubmatch(p)[1])
+		if err != nil || i >= len(enrichers) {
+			return p
+		}
+
+		return fn(enrichers[i])
+	})
+}
@@ -0,0 +1,286 @@
+package richtext
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+	"github.com/djlechuck/fvtt-packs/internal/documents"
+	"gopkg.in/yaml.v3"
+)
+
+// Extension is the extension of the files holding the rich-text fields.
+const Extension = ".html"
+
+// ReferenceKey is the key of the object replacing a rich-text field in a document, whose value is the name of the file
+// holding the field, e.g. {"$file": "Dagger_bbbbbbbbbbbbbbbb.system.description.value.html"}.
+const ReferenceKey = "$file"
+
+// frontMatterDelimiter delimits the front matter of the Markdown files.
+const frontMatterDelimiter = "---\n"
+
+// markdownFormat is the format of the journal pages written in Markdown in Foundry.
+const markdownFormat = "2"
+
+// DefaultFields are the rich-text fields of the documents, by document name.
+var DefaultFields = map[string][]string{
+	"ActiveEffect":     {"description"},
+	"Folder":           {"description"},
+	"Item":             {"system.description.value"},
+	"JournalEntryPage": {"text.content"},
+	"RollTable":        {"description"},
+}
+
+// blockTags match the tags after which a line break is added to the files, so that each block of the text is on its
+// own line.
+var blockTags = regexp.MustCompile(`(?i)</(p|div|h[1-6]|li|dt|dd|tr|table|thead|tbody|tfoot|ul|ol|dl|blockquote|section|article|aside|figure|details|summary)>|<(ul|ol|dl|table|thead|tbody|tfoot|tr|blockquote|section|article|aside|details)(\s[^>]*)?>|<hr\s*/?>`)
+
+// Pretty returns the HTML with a line break after each block tag. Compact reverses it.
+func Pretty(html string) string {
+	return blockTags.ReplaceAllString(html, "$0\n")
+}
+
+// Compact removes the line break following each block tag, if any, giving back the HTML given to Pretty.
+func Compact(html string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range blockTags.FindAllStringIndex(html, -1) {
+		b.WriteString(html[last:m[1]])
+		last = m[1]
+		if strings.HasPrefix(html[last:], "\r\n") {
+			last += 2
+		} else if strings.HasPrefix(html[last:], "\n") {
+			last++
+		}
+	}
+	b.WriteString(html[last:])
+
+	return b.String()
+}
+
+// Extract replaces the non-empty rich-text fields of the document v, decoded from JSON, and of its embedded documents,
+// by references to files named after base, the name of the document file without extension. The files have the given
+// extension: Extension for HTML, or MarkdownExtension to convert the fields to Markdown, in which case the text pages
+// of the journals are written into their own file, their metadata being in its front matter. It returns the content of
+// the files by name. fields gives the paths of the rich-text fields by document name, case-insensitively.
+func Extract(v interface{}, fields map[string][]string, base string, extension string) (map[string]string, error) {
+	files := map[string]string{}
+	if extension == MarkdownExtension {
+		if err := extractPages(v, base, files); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	docpath.EachObject(v, func(path string, obj map[string]interface{}) {
+		name, ok := documentName(obj)
+		if !ok || err != nil {
+			return
+		}
+
+		prefix := base
+		if path != "" {
+			id, _ := obj["_id"].(string)
+			prefix += "." + id
+		}
+
+		for _, p := range fieldsOf(fields, name) {
+			parent, last := parentOf(obj, p)
+			if parent == nil {
+				continue
+			}
+			text, ok := parent[last].(string)
+			if !ok || text == "" {
+				continue
+			}
+
+			file := prefix + "." + p + extension
+			if extension == MarkdownExtension {
+				if text, err = ToMarkdown(text); err != nil {
+					return
+				}
+				files[file] = text + "\n"
+			} else {
+				files[file] = Pretty(text)
+			}
+			parent[last] = map[string]interface{}{ReferenceKey: file}
+		}
+	})
+
+	return files, err
+}
+
+// extractPages replaces the text pages of the journal v by references to Markdown files, whose front matter holds the
+// metadata of the page. The pages in the Markdown format of Foundry are written as is, the others are converted.
+func extractPages(v interface{}, base string, files map[string]string) error {
+	journal, ok := v.(map[string]interface{})
+	if name, _ := documentName(journal); !ok || name != "JournalEntry" {
+		return nil
+	}
+	pages, ok := journal["pages"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	for i, p := range pages {
+		page, ok := p.(map[string]interface{})
+		if !ok || page["type"] != "text" {
+			continue
+		}
+		text, _ := page["text"].(map[string]interface{})
+		if text == nil {
+			continue
+		}
+
+		var body string
+		if format, _ := text["format"].(json.Number); format.String() == markdownFormat {
+			body, _ = text["markdown"].(string)
+			delete(text, "markdown")
+		} else {
+			content, _ := text["content"].(string)
+			converted, err := ToMarkdown(content)
+			if err != nil {
+				return err
+			}
+			body = converted + "\n"
+		}
+		delete(text, "content")
+
+		frontMatter, err := yaml.Marshal(docpath.Native(page))
+		if err != nil {
+			return err
+		}
+
+		id, _ := page["_id"].(string)
+		file := base + "." + id + MarkdownExtension
+		files[file] = frontMatterDelimiter + string(frontMatter) + frontMatterDelimiter + body
+		pages[i] = map[string]interface{}{ReferenceKey: file}
+	}
+
+	return nil
+}
+
+// Inline replaces the references to rich-text files of the document by the content of these files, read from dir.
+func Inline(doc map[string]interface{}, dir string) error {
+	_, err := inline(doc, dir)
+
+	return err
+}
+
+func inline(v interface{}, dir string) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if file, ok := val[ReferenceKey].(string); ok && len(val) == 1 {
+			return readFile(filepath.Join(dir, filepath.FromSlash(file)))
+		}
+		for k, e := range val {
+			r, err := inline(e, dir)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = r
+		}
+	case []interface{}:
+		for i, e := range val {
+			r, err := inline(e, dir)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = r
+		}
+	}
+
+	return v, nil
+}
+
+// readFile returns the rich-text field, or the journal page, held by a file.
+func readFile(file string) (interface{}, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the rich-text file %s: %s\n", file, err)
+	}
+	if !strings.EqualFold(filepath.Ext(file), MarkdownExtension) {
+		return Compact(string(data)), nil
+	}
+
+	frontMatter, body, ok := splitFrontMatter(string(data))
+	if !ok {
+		return ToHTML(body)
+	}
+
+	var page map[string]interface{}
+	if err := yaml.Unmarshal([]byte(frontMatter), &page); err != nil {
+		return nil, fmt.Errorf("cannot read the front matter of %s: %s\n", file, err)
+	}
+	text, ok := page["text"].(map[string]interface{})
+	if !ok {
+		text = map[string]interface{}{}
+		page["text"] = text
+	}
+	if fmt.Sprint(text["format"]) == markdownFormat {
+		text["markdown"] = body
+	}
+	if text["content"], err = ToHTML(body); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// splitFrontMatter returns the front matter and the body of a Markdown file, the front matter being optional.
+func splitFrontMatter(text string) (string, string, bool) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.HasPrefix(text, frontMatterDelimiter) {
+		return "", text, false
+	}
+
+	end := strings.Index(text[len(frontMatterDelimiter):], "\n"+frontMatterDelimiter)
+	if end < 0 {
+		return "", text, false
+	}
+	end += len(frontMatterDelimiter)
+
+	return text[len(frontMatterDelimiter) : end+1], text[end+1+len(frontMatterDelimiter):], true
+}
+
+// documentName returns the name of the type of a document, found from its _key.
+func documentName(obj map[string]interface{}) (string, bool) {
+	key, ok := obj["_key"].(string)
+	if !ok {
+		return "", false
+	}
+	parts := strings.Split(key, "!")
+	if len(parts) < 3 {
+		return "", false
+	}
+
+	return documents.DocumentName(parts[1])
+}
+
+func fieldsOf(fields map[string][]string, name string) []string {
+	for n, f := range fields {
+		if strings.EqualFold(n, name) {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// parentOf returns the object holding the field at the dotted path, and the key of the field in it.
+func parentOf(obj map[string]interface{}, path string) (map[string]interface{}, string) {
+	segments := strings.Split(path, ".")
+	for _, s := range segments[:len(segments)-1] {
+		child, ok := obj[s].(map[string]interface{})
+		if !ok {
+			return nil, ""
+		}
+		obj = child
+	}
+
+	return obj, segments[len(segments)-1]
+}
@@ -0,0 +1,382 @@
+package search
+
+import (
+	"html"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+	"github.com/djlechuck/fvtt-packs/internal/documents"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ExtraFields are the text fields indexed besides the rich-text fields, by document name.
+var ExtraFields = map[string][]string{
+	"TableResult": {"text", "description"},
+}
+
+// nameField is the field holding the name of the documents, which weighs more than the other fields.
+const nameField = "name"
+
+// nameWeight is the weight of the name field in the scores.
+const nameWeight = 2
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// snippetBefore and snippetAfter are the numbers of characters of the snippets before and after the first match.
+const (
+	snippetBefore = 30
+	snippetAfter  = 60
+)
+
+var (
+	// enrichers match the Foundry enrichers like @UUID[Compendium.my-module.items.Item.id]{Label}, whose label is kept.
+	enrichers = regexp.MustCompile(`@\w+\[[^\]]*\](\{([^}]*)\})?`)
+	tags      = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Field is an indexed text field of a document.
+type Field struct {
+	Pack string
+	// Key is the LevelDB key of the document, which may be embedded.
+	Key string
+	// Document is the name of the document, preceded by the ones of its parents, e.g. "Goblin > Scimitar".
+	Document string
+	// Path is the dotted path of the field in the document, e.g. "system.description.value".
+	Path string
+	// Text is the text of the field, without HTML.
+	Text string
+}
+
+// Result is a field matching a query.
+type Result struct {
+	Field
+	Score   float64
+	Snippet string
+}
+
+// Index is an inverted index of the text fields of documents.
+type Index struct {
+	fields      []Field
+	lengths     []int
+	totalLength int
+	// postings are the positions of the terms in the fields, by term and field.
+	postings map[string]map[int][]int
+	// richText are the rich-text fields of the documents, by document name.
+	richText map[string][]string
+}
+
+// NewIndex returns an empty index of the names of the documents and of the given rich-text fields, by document name,
+// and of ExtraFields.
+func NewIndex(richText map[string][]string) *Index {
+	fields := map[string][]string{}
+	for name, paths := range richText {
+		fields[strings.ToLower(name)] = append(fields[strings.ToLower(name)], paths...)
+	}
+	for name, paths := range ExtraFields {
+		fields[strings.ToLower(name)] = append(fields[strings.ToLower(name)], paths...)
+	}
+
+	return &Index{postings: map[string]map[int][]int{}, richText: fields}
+}
+
+// AddDocument indexes a document, with its _key and its embedded documents, found in the given pack.
+func (idx *Index) AddDocument(pack string, doc map[string]interface{}) {
+	idx.addDocument(pack, doc, "")
+}
+
+func (idx *Index) addDocument(pack string, doc map[string]interface{}, parent string) {
+	key, _ := doc["_key"].(string)
+	name, _ := doc[nameField].(string)
+	label := name
+	if parent != "" && name != "" {
+		label = parent + " > " + name
+	} else if parent != "" {
+		// Table results have no name.
+		label = parent
+	}
+
+	idx.Add(Field{Pack: pack, Key: key, Document: label, Path: nameField, Text: name})
+	if docName, ok := documentName(key); ok {
+		for _, path := range idx.richText[strings.ToLower(docName)] {
+			if text, ok := docpath.Get(doc, path); ok {
+				if s, ok := text.(string); ok {
+					idx.Add(Field{Pack: pack, Key: key, Document: label, Path: path, Text: PlainText(s)})
+				}
+			}
+		}
+	}
+
+	for _, field := range sortedKeys(doc) {
+		children, ok := doc[field].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, c := range children {
+			if child, ok := c.(map[string]interface{}); ok {
+				if _, ok := child["_key"].(string); ok {
+					idx.addDocument(pack, child, label)
+				}
+			}
+		}
+	}
+}
+
+// Add indexes a text field.
+func (idx *Index) Add(f Field) {
+	tokens := tokenize(f.Text)
+	if len(tokens) == 0 {
+		return
+	}
+
+	i := len(idx.fields)
+	idx.fields = append(idx.fields, f)
+	idx.lengths = append(idx.lengths, len(tokens))
+	idx.totalLength += len(tokens)
+
+	for p, t := range tokens {
+		if idx.postings[t.term] == nil {
+			idx.postings[t.term] = map[int][]int{}
+		}
+		idx.postings[t.term][i] = append(idx.postings[t.term][i], p)
+	}
+}
+
+// Search returns the fields matching the query, best first, at most limit if it is positive. A field matches when it
+// contains every word of the query, case and diacritics being ignored. Words between double quotes must follow each
+// other, e.g. "magic missile".
+func (idx *Index) Search(q string, limit int) []Result {
+	phrases := parseQuery(q)
+	if len(phrases) == 0 {
+		return nil
+	}
+
+	// Candidate fields contain every term, then every phrase.
+	var candidates map[int]bool
+	for _, phrase := range phrases {
+		for _, term := range phrase {
+			found := map[int]bool{}
+			for field := range idx.postings[term] {
+				if candidates == nil || candidates[field] {
+					found[field] = true
+				}
+			}
+			candidates = found
+		}
+	}
+
+	avgLength := float64(idx.totalLength) / float64(max(len(idx.fields), 1))
+	var results []Result
+	for field := range candidates {
+		score := 0.0
+		matched := true
+		for _, phrase := range phrases {
+			if len(phrase) > 1 && !idx.containsPhrase(field, phrase) {
+				matched = false
+				break
+			}
+			for _, term := range phrase {
+				score += idx.termScore(term, field, avgLength)
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		f := idx.fields[field]
+		if f.Path == nameField {
+			score *= nameWeight
+		}
+		results = append(results, Result{Field: f, Score: score, Snippet: snippet(f.Text, phrases[0])})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Document != results[j].Document {
+			return results[i].Document < results[j].Document
+		}
+		return results[i].Path < results[j].Path
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// termScore returns the BM25 score of a term for a field.
+func (idx *Index) termScore(term string, field int, avgLength float64) float64 {
+	postings := idx.postings[term]
+	n := float64(len(idx.fields))
+	df := float64(len(postings))
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+	tf := float64(len(postings[field]))
+	length := float64(idx.lengths[field])
+
+	return idf * tf * (k1 + 1) / (tf + k1*(1-b+b*length/avgLength))
+}
+
+// containsPhrase reports whether the terms of the phrase follow each other in the field.
+func (idx *Index) containsPhrase(field int, phrase []string) bool {
+	positions := make([]map[int]bool, len(phrase))
+	for i, term := range phrase {
+		positions[i] = map[int]bool{}
+		for _, pos := range idx.postings[term][field] {
+			positions[i][pos] = true
+		}
+	}
+
+	for start := range positions[0] {
+		found := true
+		for i := 1; i < len(phrase) && found; i++ {
+			found = positions[i][start+i]
+		}
+		if found {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseQuery returns the phrases of a query: the words between double quotes, and every other word on its own.
+func parseQuery(q string) [][]string {
+	var phrases [][]string
+	for i, part := range strings.Split(q, `"`) {
+		var terms []string
+		for _, t := range tokenize(part) {
+			terms = append(terms, t.term)
+		}
+		if i%2 == 1 {
+			if len(terms) > 0 {
+				phrases = append(phrases, terms)
+			}
+			continue
+		}
+		for _, term := range terms {
+			phrases = append(phrases, []string{term})
+		}
+	}
+
+	return phrases
+}
+
+// snippet returns the part of the text around the first occurrence of the phrase, on a single line.
+func snippet(text string, phrase []string) string {
+	tokens := tokenize(text)
+	start, end := 0, 0
+	for i := range tokens {
+		if i+len(phrase) > len(tokens) {
+			break
+		}
+		match := true
+		for j, term := range phrase {
+			if tokens[i+j].term != term {
+				match = false
+				break
+			}
+		}
+		if match {
+			start, end = tokens[i].start, tokens[i+len(phrase)-1].end
+			break
+		}
+	}
+
+	before := []rune(text[:start])
+	after := []rune(text[end:])
+	prefix, suffix := "", ""
+	if len(before) > snippetBefore {
+		before = before[len(before)-snippetBefore:]
+		prefix = "…"
+	}
+	if len(after) > snippetAfter {
+		after = after[:snippetAfter]
+		suffix = "…"
+	}
+
+	s := prefix + string(before) + text[start:end] + string(after) + suffix
+
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// PlainText returns the text of an HTML field, without tags, and with the labels of its enrichers, or their target
+// when they have none.
+func PlainText(s string) string {
+	s = enrichers.ReplaceAllStringFunc(s, func(m string) string {
+		parts := enrichers.FindStringSubmatch(m)
+		if parts[1] != "" {
+			return parts[2]
+		}
+		target := m[strings.Index(m, "[")+1 : len(m)-1]
+		return target[strings.LastIndex(target, ".")+1:]
+	})
+	s = tags.ReplaceAllString(s, " ")
+
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+type token struct {
+	term  string
+	start int
+	end   int
+}
+
+// tokenize splits a text into words, lower cased and without diacritics, with their position in the text.
+func tokenize(s string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range s + " " {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			if term := normalize(s[start:i]); term != "" {
+				tokens = append(tokens, token{term: term, start: start, end: i})
+			}
+			start = -1
+		}
+	}
+
+	return tokens
+}
+
+func normalize(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(word) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+
+	return b.String()
+}
+
+// documentName returns the name of the type of a document, found from its _key.
+func documentName(key string) (string, bool) {
+	parts := strings.Split(key, "!")
+	if len(parts) < 3 {
+		return "", false
+	}
+
+	return documents.DocumentName(parts[1])
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,146 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+var packDocuments = map[string][]string{
+	"items": {
+		`{"_key": "!items!aaaaaaaaaaaaaaaa", "name": "Magic Missile", "system": {"description": {"value":
+			"<p>Three glowing darts. See @UUID[Compendium.my-mod.items.Item.bbbbbbbbbbbbbbbb]{Shield}.</p>"}}}`,
+		`{"_key": "!items!bbbbbbbbbbbbbbbb", "name": "Missile Weapon", "system": {"description": {"value":
+			"<p>A simple missile.</p>"}}}`,
+		`{"_key": "!items!cccccccccccccccc", "name": "Élan vital", "system": {"description": {"value":
+			"<p>Restores vigor.</p>"}}}`,
+	},
+	"actors": {
+		`{"_key": "!actors!dddddddddddddddd", "name": "Goblin", "items": [{"_key": "!actors.items!dddddddddddddddd.eeeeeeeeeeeeeeee",
+			"name": "Scimitar", "system": {"description": {"value": "<p>A curved magic blade, no missile.</p>"}}}]}`,
+	},
+	"tables": {
+		`{"_key": "!tables!ffffffffffffffff", "name": "Treasure", "description": "",
+			"results": [{"_key": "!tables.results!ffffffffffffffff.gggggggggggggggg", "text": "Magic missile wand"}]}`,
+	},
+}
+
+func newTestIndex(t *testing.T) *Index {
+	idx := NewIndex(map[string][]string{"Item": {"system.description.value"}, "RollTable": {"description"}})
+	for _, pack := range []string{"items", "actors", "tables"} {
+		for _, s := range packDocuments[pack] {
+			v, err := docpath.Decode([]byte(s))
+			if err != nil {
+				t.Fatal(err)
+			}
+			idx.AddDocument(pack, v.(map[string]interface{}))
+		}
+	}
+
+	return idx
+}
+
+func TestSearch(t *testing.T) {
+	idx := newTestIndex(t)
+
+	tests := []struct {
+		query    string
+		limit    int
+		expected []string
+	}{
+		{"missile", 0, []string{
+			"items Magic Missile name",
+			"items Missile Weapon name",
+			"items Missile Weapon system.description.value",
+			"tables Treasure text",
+			"actors Goblin > Scimitar system.description.value",
+		}},
+		{"missile", 2, []string{"items Magic Missile name", "items Missile Weapon name"}},
+		{"MISSILE magic", 0, []string{
+			"items Magic Missile name",
+			"tables Treasure text",
+			"actors Goblin > Scimitar system.description.value",
+		}},
+		{`"magic missile"`, 0, []string{"items Magic Missile name", "tables Treasure text"}},
+		{`"missile magic"`, 0, nil},
+		{"elan", 0, []string{"items Élan vital name"}},
+		{"ÉLAN", 0, []string{"items Élan vital name"}},
+		{"shield", 0, []string{"items Magic Missile system.description.value"}},
+		{"compendium", 0, nil},
+		{"curved", 0, []string{"actors Goblin > Scimitar system.description.value"}},
+		{"vigor missing", 0, nil},
+		{"", 0, nil},
+		{`""`, 0, nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, r := range idx.Search(tt.query, tt.limit) {
+			got = append(got, r.Pack+" "+r.Document+" "+r.Path)
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("Search(%s, %d) = %q, expected %q", tt.query, tt.limit, got, tt.expected)
+		}
+	}
+}
+
+func TestSearchResult(t *testing.T) {
+	results := newTestIndex(t).Search("curved", 0)
+	if len(results) != 1 {
+		t.Fatalf("Search(curved) = %v, expected 1 result", results)
+	}
+
+	r := results[0]
+	if r.Key != "!actors.items!dddddddddddddddd.eeeeeeeeeeeeeeee" {
+		t.Errorf("Search(curved) gives the key %s", r.Key)
+	}
+	if r.Text != "A curved magic blade, no missile." || r.Snippet != r.Text {
+		t.Errorf("Search(curved) gives the text %q and the snippet %q", r.Text, r.Snippet)
+	}
+	if r.Score <= 0 {
+		t.Errorf("Search(curved) gives the score %f", r.Score)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		html     string
+		expected string
+	}{
+		{"<p>Hello <strong>world</strong></p>", "Hello world"},
+		{"<p>First</p><p>Second</p>", "First Second"},
+		{"See @UUID[Compendium.my-mod.items.Item.bbbbbbbbbbbbbbbb]{Shield}.", "See Shield."},
+		{"See @UUID[Compendium.my-mod.items.Item.bbbbbbbbbbbbbbbb].", "See bbbbbbbbbbbbbbbb."},
+		{"Make a @Check[dex] check", "Make a dex check"},
+		{"Fish &amp; chips", "Fish & chips"},
+		{"a\n\n  b\t", "a b"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := PlainText(tt.html); got != tt.expected {
+			t.Errorf("PlainText(%q) = %q, expected %q", tt.html, got, tt.expected)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("a ", 30) + "target" + strings.Repeat(" b", 40)
+
+	tests := []struct {
+		text     string
+		phrase   []string
+		expected string
+	}{
+		{"short text here", []string{"text"}, "short text here"},
+		{"Un élan\nvital", []string{"elan", "vital"}, "Un élan vital"},
+		{long, []string{"target"}, "…" + strings.TrimSpace(strings.Repeat("a ", 15)) + " target" + strings.Repeat(" b", 30) + "…"},
+	}
+
+	for _, tt := range tests {
+		if got := snippet(tt.text, tt.phrase); got != tt.expected {
+			t.Errorf("snippet(%q, %q) = %q, expected %q", tt.text, tt.phrase, got, tt.expected)
+		}
+	}
+}
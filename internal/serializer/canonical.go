@@ -0,0 +1,200 @@
+package serializer
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+	"gopkg.in/yaml.v3"
+)
+
+// canonicalEncoder is implemented by the formats having their own canonical serialization.
+type canonicalEncoder interface {
+	// EncodeCanonical returns the content of the file of a canonical document, as returned by canonicalValue.
+	EncodeCanonical(v interface{}) ([]byte, error)
+}
+
+type canonicalFormat struct {
+	Format
+}
+
+// Canonical returns the format writing the documents in a canonical form, so that unpacking the same documents always
+// gives the same files, however they have been edited: keys are sorted, numbers are written in their shortest form
+// without exponent, null lists and objects of the documents are written as empty ones, and HTML is not escaped. YAML
+// files also use block scalars for multi-line strings.
+func Canonical(f Format) Format {
+	if _, ok := f.(canonicalFormat); ok {
+		return f
+	}
+
+	return canonicalFormat{f}
+}
+
+func (f canonicalFormat) Encode(v interface{}) ([]byte, error) {
+	c, err := canonicalValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if e, ok := f.Format.(canonicalEncoder); ok {
+		return e.EncodeCanonical(c)
+	}
+
+	return f.Format.Encode(c)
+}
+
+// canonicalValue returns the document as a decoded JSON value, its numbers being normalized.
+func canonicalValue(v interface{}) (interface{}, error) {
+	emptyCollections(reflect.ValueOf(v))
+
+	data, err := docpath.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	c, err := docpath.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return normalizeNumbers(c), nil
+}
+
+// emptyCollections replaces the nil slices and maps of the structs by empty ones, as they would otherwise be written as
+// null depending on whether the field was missing or empty in the database.
+func emptyCollections(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			emptyCollections(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			switch {
+			case f.Kind() == reflect.Slice && f.IsNil():
+				f.Set(reflect.MakeSlice(f.Type(), 0, 0))
+			case f.Kind() == reflect.Map && f.IsNil():
+				f.Set(reflect.MakeMap(f.Type()))
+			default:
+				emptyCollections(f)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			emptyCollections(v.Index(i))
+		}
+	}
+}
+
+// normalizeNumbers replaces the numbers of a decoded JSON value by their shortest form, e.g. 1000000 for 1e+06 or 1 for
+// 1.0.
+func normalizeNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		return normalizeNumber(val)
+	case map[string]interface{}:
+		for k := range val {
+			val[k] = normalizeNumbers(val[k])
+		}
+	case []interface{}:
+		for i := range val {
+			val[i] = normalizeNumbers(val[i])
+		}
+	}
+
+	return v
+}
+
+func normalizeNumber(n json.Number) json.Number {
+	if _, err := n.Int64(); err == nil {
+		return n
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return n
+	}
+	// encoding/json writes floats the way JavaScript does, only using an exponent for very small or large numbers.
+	data, err := json.Marshal(f)
+	if err != nil {
+		return n
+	}
+
+	return json.Number(data)
+}
+
+func (jsonFormat) EncodeCanonical(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func (yamlFormat) EncodeCanonical(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNode(v)); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// yamlNode returns the YAML node of a canonical value. Strings are always read back as strings, quoting them when
+// needed, and multi-line strings are written as literal block scalars when possible.
+func yamlNode(v interface{}) *yaml.Node {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			n.Content = append(n.Content, yamlNode(k), yamlNode(val[k]))
+		}
+		return n
+	case []interface{}:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, e := range val {
+			n.Content = append(n.Content, yamlNode(e))
+		}
+		return n
+	case string:
+		n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: val}
+		if strings.Contains(val, "\n") {
+			n.Style = yaml.LiteralStyle
+		}
+		return n
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(val.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: val.String()}
+	case bool:
+		if val {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "false"}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+}
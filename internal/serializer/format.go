@@ -0,0 +1,115 @@
+package serializer
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a file format of the unpacked documents.
+type Format interface {
+	// Name is the name of the format, as given in the flags and the config file.
+	Name() string
+	// Extensions are the file extensions of the format, the first one being used for the written files.
+	Extensions() []string
+	// Encode returns the content of the file of a document.
+	Encode(v interface{}) ([]byte, error)
+	// Decode returns the document contained in a file.
+	Decode(data []byte) (interface{}, error)
+}
+
+var formats = map[string]Format{}
+
+// Register makes a format available by its name and extensions.
+func Register(f Format) {
+	formats[f.Name()] = f
+}
+
+// Get returns the format with the given name.
+func Get(name string) (Format, error) {
+	f, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format \"%s\", available formats are %s\n", name, strings.Join(Names(), ", "))
+	}
+
+	return f, nil
+}
+
+// Names returns the names of the registered formats.
+func Names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ForFile returns the format of a file, detected from its extension.
+func ForFile(file string) (Format, bool) {
+	ext := path.Ext(file)
+	for _, f := range formats {
+		for _, e := range f.Extensions() {
+			if e == ext {
+				return f, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// Extension returns the extension of the files written in the format.
+func Extension(f Format) string {
+	return f.Extensions()[0]
+}
+
+type jsonFormat struct{}
+
+func (jsonFormat) Name() string {
+	return "json"
+}
+
+func (jsonFormat) Extensions() []string {
+	return []string{".json"}
+}
+
+func (jsonFormat) Encode(v interface{}) ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func (jsonFormat) Decode(data []byte) (interface{}, error) {
+	return docpath.Decode(data)
+}
+
+type yamlFormat struct{}
+
+func (yamlFormat) Name() string {
+	return "yaml"
+}
+
+func (yamlFormat) Extensions() []string {
+	return []string{".yml", ".yaml"}
+}
+
+func (yamlFormat) Encode(v interface{}) ([]byte, error) {
+	return yaml.Marshal(docpath.Native(v))
+}
+
+func (yamlFormat) Decode(data []byte) (interface{}, error) {
+	var v interface{}
+	err := yaml.Unmarshal(data, &v)
+
+	return v, err
+}
+
+func init() {
+	Register(jsonFormat{})
+	Register(yamlFormat{})
+}
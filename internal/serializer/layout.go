@@ -0,0 +1,64 @@
+package serializer
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/documents"
+	"github.com/djlechuck/fvtt-packs/internal/slug"
+)
+
+// FolderFileName is the name, without extension, of the file holding the metadata of a folder in its directory.
+const FolderFileName = "_folder"
+
+// FolderTree gives the directories mirroring the folders of a pack, relative to its sources directory.
+type FolderTree struct {
+	dirs map[string]string
+}
+
+// NewFolderTree builds the directories of the folders. A folder whose name is already used by a sibling folder gets its
+// id appended to its directory name.
+func NewFolderTree(folders []*documents.FolderDocument) *FolderTree {
+	byId := map[string]*documents.FolderDocument{}
+	for _, f := range folders {
+		byId[f.Id] = f
+	}
+
+	// Sort the folders so that the disambiguation of siblings does not depend on the reading order.
+	sorted := append([]*documents.FolderDocument(nil), folders...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+
+	names := map[string]string{}
+	used := map[string]bool{}
+	for _, f := range sorted {
+		name := slug.Make(f.Name)
+		if name == "" {
+			name = f.Id
+		}
+		siblingKey := f.Folder + "/" + strings.ToLower(name)
+		if used[siblingKey] {
+			name += "_" + f.Id
+		}
+		used[siblingKey] = true
+		names[f.Id] = name
+	}
+
+	t := &FolderTree{dirs: map[string]string{}}
+	for _, f := range folders {
+		var parts []string
+		seen := map[string]bool{}
+		for current := f; current != nil && !seen[current.Id]; current = byId[current.Folder] {
+			seen[current.Id] = true
+			parts = append([]string{names[current.Id]}, parts...)
+		}
+		t.dirs[f.Id] = path.Join(parts...)
+	}
+
+	return t
+}
+
+// Directory returns the directory of the folder, or an empty string if the folder is unknown.
+func (t *FolderTree) Directory(folderId string) string {
+	return t.dirs[folderId]
+}
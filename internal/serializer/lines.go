@@ -0,0 +1,103 @@
+package serializer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+// LinesExtension is the extension of the JSON Lines files, holding every document of a pack, one per line.
+const LinesExtension = ".jsonl"
+
+// maxLineSize is the maximum size of a document of a JSON Lines file.
+const maxLineSize = 64 * 1024 * 1024
+
+// Line is the format of the documents of the JSON Lines files: compact JSON, written on a single line. It is not
+// registered, as a file holds several documents.
+var Line Format = lineFormat{}
+
+type lineFormat struct{}
+
+func (lineFormat) Name() string {
+	return "jsonl"
+}
+
+func (lineFormat) Extensions() []string {
+	return []string{LinesExtension}
+}
+
+func (lineFormat) Encode(v interface{}) ([]byte, error) {
+	return docpath.Encode(v)
+}
+
+func (lineFormat) Decode(data []byte) (interface{}, error) {
+	return docpath.Decode(data)
+}
+
+// WriteLines writes the documents, encoded with Line, into a JSON Lines file, sorted by id.
+func WriteLines(file string, lines map[string][]byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, JoinLines(lines), 0644)
+}
+
+// JoinLines returns the content of a JSON Lines file holding the documents, encoded with Line, sorted by id.
+func JoinLines(lines map[string][]byte) []byte {
+	ids := make([]string, 0, len(lines))
+	for id := range lines {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var buf bytes.Buffer
+	for _, id := range ids {
+		buf.Write(lines[id])
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
+
+// ReadLines reads the documents of a JSON Lines file. Empty lines are ignored.
+func ReadLines(file string) ([]map[string]interface{}, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var docs []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		v, err := docpath.Decode(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s\n", n, err)
+		}
+		doc, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("line %d does not contain a document\n", n)
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs, scanner.Err()
+}
+
+// IsLines reports whether the file is a JSON Lines file, according to its extension.
+func IsLines(file string) bool {
+	return path.Ext(file) == LinesExtension
+}
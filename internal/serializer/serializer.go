@@ -1,37 +1,108 @@
 package serializer
 
 import (
-	"encoding/json"
+	"fmt"
 	"github.com/djlechuck/fvtt-packs/internal/documents"
-	"gopkg.in/yaml.v3"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 )
 
-func SerializeDocument(doc *documents.Document, destination string, isYaml bool) error {
-	if err := os.MkdirAll(destination, 0755); err != nil {
-		return err
+// Namer gives unique file names to the documents of a pack. File names differing only by their case are considered
+// the same, as they are on Windows and macOS: the documents coming after the first one get their id, then a number,
+// appended to their name.
+type Namer struct {
+	template string
+	used     map[string]bool
+}
+
+func NewNamer(template string) *Namer {
+	if template == "" {
+		template = documents.DefaultFilename
 	}
 
-	var serialized []byte
-	var err error
+	return &Namer{template: template, used: map[string]bool{}}
+}
 
-	if isYaml {
-		serialized, err = yaml.Marshal(doc)
-		if err != nil {
-			return err
-		}
-	} else {
-		serialized, err = json.MarshalIndent(doc, "", "  ")
-		if err != nil {
-			return err
+// Name returns the file name of the document inside the given directory, both relative to the sources directory of its
+// pack.
+func (n *Namer) Name(dir string, doc *documents.Document, format Format) string {
+	return n.unique(path.Join(dir, documents.ExportName(*doc, n.template, Extension(format))), (*doc).GetId())
+}
+
+// NameOf returns the file name of a document given by its name, id and type, as Name does.
+func (n *Namer) NameOf(dir string, name string, id string, docType string, format Format) string {
+	return n.unique(path.Join(dir, documents.FileName(name, id, docType, n.template, Extension(format))), id)
+}
+
+// Reserve marks a file name as used, e.g. by a file which already exists.
+func (n *Namer) Reserve(name string) {
+	n.used[strings.ToLower(name)] = true
+}
+
+func (n *Namer) unique(name string, id string) string {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	for i := 1; n.used[strings.ToLower(name)]; i++ {
+		name = stem + "_" + id + ext
+		if i > 1 {
+			name = fmt.Sprintf("%s_%s_%d%s", stem, id, i, ext)
 		}
 	}
+	n.used[strings.ToLower(name)] = true
+
+	return name
+}
+
+// SerializeDocument writes the document into the given file, creating its directory if needed.
+func SerializeDocument(doc *documents.Document, file string, format Format) error {
+	return Write(doc, file, format)
+}
+
+// Write writes the value into the given file, creating its directory if needed.
+func Write(v interface{}, file string, format Format) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
 
-	err = os.WriteFile(path.Join(destination, (*doc).ExportName(isYaml)), append(serialized, '\n'), 0644)
+	serialized, err := format.Encode(v)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return os.WriteFile(file, append(serialized, '\n'), 0644)
+}
+
+// ReadSource reads a source document, in the format matching its extension.
+func ReadSource(file string) (map[string]interface{}, error) {
+	format, ok := ForFile(file)
+	if !ok {
+		return nil, fmt.Errorf("unsupported source file %s\n", file)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := format.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s does not contain a document\n", file)
+	}
+
+	return m, nil
+}
+
+// IsSource reports whether the file is a source document, according to its extension.
+func IsSource(file string) bool {
+	_, ok := ForFile(file)
+
+	return ok
 }
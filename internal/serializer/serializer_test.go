@@ -0,0 +1,30 @@
+package serializer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteCreatesDirectories(t *testing.T) {
+	format, err := Get("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	tests := []string{
+		filepath.Join(dir, "Dagger.json"),
+		filepath.Join(dir, "weapon", "Dagger.json"),
+		filepath.Join(dir, "Armory", "Blades", "weapon", "Dagger.json"),
+	}
+	for _, file := range tests {
+		if err := Write(map[string]interface{}{"name": "Dagger"}, file, format); err != nil {
+			t.Errorf("Write(%s): %s", file, err)
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("Write(%s) wrote no file: %s", file, err)
+		}
+	}
+}
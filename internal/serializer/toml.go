@@ -0,0 +1,87 @@
+package serializer
+
+import (
+	"encoding/json"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// tomlFormat writes the documents as TOML. TOML having no null value, null values are written as {"$null" = true},
+// which is read back as null.
+type tomlFormat struct{}
+
+// nullKey is the key of the table standing for a null value.
+const nullKey = "$null"
+
+func (tomlFormat) Name() string {
+	return "toml"
+}
+
+func (tomlFormat) Extensions() []string {
+	return []string{".toml"}
+}
+
+func (tomlFormat) Encode(v interface{}) ([]byte, error) {
+	// Go through JSON so that the documents keep the field names of their JSON tags.
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	generic, err := docpath.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return toml.Marshal(encodeNulls(docpath.Native(generic)))
+}
+
+func (tomlFormat) Decode(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := toml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	return decodeNulls(v), nil
+}
+
+// encodeNulls replaces the null values of a decoded document by a table holding nullKey.
+func encodeNulls(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return map[string]interface{}{nullKey: true}
+	case map[string]interface{}:
+		for k := range val {
+			val[k] = encodeNulls(val[k])
+		}
+	case []interface{}:
+		for i := range val {
+			val[i] = encodeNulls(val[i])
+		}
+	}
+
+	return v
+}
+
+// decodeNulls replaces the tables written by encodeNulls by null values.
+func decodeNulls(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if isNull, ok := val[nullKey].(bool); ok && isNull && len(val) == 1 {
+			return nil
+		}
+		for k := range val {
+			val[k] = decodeNulls(val[k])
+		}
+	case []interface{}:
+		for i := range val {
+			val[i] = decodeNulls(val[i])
+		}
+	}
+
+	return v
+}
+
+func init() {
+	Register(tomlFormat{})
+}
@@ -0,0 +1,45 @@
+package serializer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+func TestTomlRoundTrip(t *testing.T) {
+	tests := []string{
+		`{"name":"Dagger","folder":null}`,
+		`{"b":[1,null]}`,
+		`{"a":{"b":null,"c":[null,{"d":null}]},"e":[]}`,
+		`{"system":{"price":{"value":1.5,"denomination":"gp"},"quantity":2},"effects":[{"_id":"x","disabled":false}]}`,
+		`{"flags":{}}`,
+	}
+
+	format, err := Get("toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range tests {
+		v, err := docpath.Decode([]byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, _ := json.Marshal(docpath.Native(v))
+		data, err := format.Encode(v)
+		if err != nil {
+			t.Errorf("cannot encode %s: %s", doc, err)
+			continue
+		}
+		decoded, err := format.Decode(data)
+		if err != nil {
+			t.Errorf("cannot decode %s: %s\n%s", doc, err, data)
+			continue
+		}
+
+		got, _ := json.Marshal(decoded)
+		if string(got) != string(expected) {
+			t.Errorf("%s gave back %s, written as\n%s", doc, got, data)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package serializer
+
+import (
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+type transformedFormat struct {
+	Format
+	fn func(v interface{}) error
+}
+
+// Transform returns the format modifying the documents with fn before writing them. fn is given the document decoded
+// as JSON, and modifies it in place, e.g. to leave out some fields. An error returned by fn stops the writing. To be
+// written in a canonical form too, the returned format must be given to Canonical.
+func Transform(f Format, fn func(v interface{}) error) Format {
+	return transformedFormat{Format: f, fn: fn}
+}
+
+func (f transformedFormat) Encode(v interface{}) ([]byte, error) {
+	data, err := docpath.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	generic, err := docpath.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.fn(generic); err != nil {
+		return nil, err
+	}
+
+	return f.Format.Encode(generic)
+}
+
+func (f transformedFormat) EncodeCanonical(v interface{}) ([]byte, error) {
+	if err := f.fn(v); err != nil {
+		return nil, err
+	}
+
+	if e, ok := f.Format.(canonicalEncoder); ok {
+		return e.EncodeCanonical(v)
+	}
+
+	return f.Format.Encode(v)
+}
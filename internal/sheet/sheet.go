@@ -0,0 +1,69 @@
+package sheet
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+// Cell returns the text of a spreadsheet cell holding the value v, decoded from JSON. Objects and lists are written as
+// JSON, null as an empty cell.
+func Cell(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		data, err := docpath.Encode(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(data)
+	}
+}
+
+// Parse returns the value of a spreadsheet cell replacing the previous value of a field, so that the field keeps its
+// type: a number stays a number, an object stays an object... The type of a field without value is guessed from the
+// cell, which is a string unless it is valid JSON. An empty cell gives an empty string to a string field, and null
+// otherwise.
+func Parse(cell string, previous interface{}) (interface{}, error) {
+	if _, ok := previous.(string); ok {
+		return cell, nil
+	}
+	if strings.TrimSpace(cell) == "" {
+		return nil, nil
+	}
+
+	switch previous.(type) {
+	case json.Number:
+		var n json.Number
+		if err := json.Unmarshal([]byte(strings.TrimSpace(cell)), &n); err != nil {
+			return nil, fmt.Errorf("\"%s\" is not a number", cell)
+		}
+		return n, nil
+	case bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(cell))
+		if err != nil {
+			return nil, fmt.Errorf("\"%s\" is not a boolean", cell)
+		}
+		return b, nil
+	case map[string]interface{}, []interface{}:
+		if !json.Valid([]byte(cell)) {
+			return nil, fmt.Errorf("\"%s\" is not valid JSON", cell)
+		}
+		return docpath.Decode([]byte(cell))
+	default:
+		if json.Valid([]byte(cell)) {
+			return docpath.Decode([]byte(cell))
+		}
+		return cell, nil
+	}
+}
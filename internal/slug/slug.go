@@ -0,0 +1,98 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the maximum length, in characters, of a slug.
+const MaxLength = 64
+
+// transliterations are the ASCII equivalents of the lower case letters which do not decompose into an ASCII letter
+// and diacritics.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th", 'ı': "i",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'ё': "yo", 'є': "ye", 'ж': "zh",
+	'з': "z", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh",
+	'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// windowsReserved are the file names Windows forbids, whatever their extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Make returns a string usable in a file name: letters are transliterated to ASCII when possible, letters of other
+// scripts and digits are kept, and any other sequence of characters is replaced by an underscore. The slug is at most
+// MaxLength characters long and is never a name reserved by Windows.
+func Make(s string) string {
+	var b strings.Builder
+	pendingSeparator := false
+
+	write := func(r rune) {
+		if pendingSeparator && b.Len() > 0 {
+			b.WriteRune('_')
+		}
+		pendingSeparator = false
+		b.WriteRune(r)
+	}
+
+	// transliterate writes the ASCII equivalent of a letter, and reports whether it has one.
+	transliterate := func(r rune) bool {
+		t, ok := transliterations[unicode.ToLower(r)]
+		for i, tr := range t {
+			if i == 0 && unicode.IsUpper(r) {
+				tr = unicode.ToUpper(tr)
+			}
+			write(tr)
+		}
+		return ok
+	}
+
+	// Letters are looked up before being decomposed, as some letters to transliterate decompose, e.g. й into и and a
+	// breve.
+	for _, c := range norm.NFC.String(s) {
+		if transliterate(c) {
+			continue
+		}
+
+		for _, r := range norm.NFD.String(string(c)) {
+			switch {
+			case unicode.Is(unicode.Mn, r):
+				// Diacritics of the previous letter, already written without them.
+			case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+				write(r)
+			case unicode.IsLetter(r) || unicode.IsDigit(r):
+				if !transliterate(r) {
+					write(r)
+				}
+			default:
+				pendingSeparator = true
+			}
+		}
+	}
+
+	slug := []rune(b.String())
+	if len(slug) > MaxLength {
+		slug = []rune(strings.TrimRight(string(slug[:MaxLength]), "_"))
+	}
+
+	result := string(slug)
+	if windowsReserved[strings.ToUpper(result)] {
+		result += "_"
+	}
+
+	return result
+}
+
+// IsWindowsReserved reports whether a file name is forbidden by Windows, whatever its extension.
+func IsWindowsReserved(name string) bool {
+	base, _, _ := strings.Cut(name, ".")
+
+	return windowsReserved[strings.ToUpper(base)]
+}
@@ -0,0 +1,59 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"Dagger", "Dagger"},
+		{"Épée longue", "Epee_longue"},
+		{"Straße", "Strasse"},
+		{"Œuvre", "Oeuvre"},
+		{"Йод", "Yod"},
+		{"йод", "yod"},
+		{"Ёлка", "Yolka"},
+		{"Їжак", "Yizhak"},
+		{"Й", "Y"},
+		{"Меч-кладенец", "Mech_kladenets"},
+		{"Объект", "Obekt"},
+		// Decomposed input is composed before the lookup.
+		{"\u0418\u0306од", "Yod"},
+		{"剣 の 舞", "剣_の_舞"},
+		{"  Magic Missile (1st level)  ", "Magic_Missile_1st_level"},
+		{"con", "con_"},
+		{"Lpt1", "Lpt1_"},
+		{"...", ""},
+		{strings.Repeat("a", 70), strings.Repeat("a", MaxLength)},
+		{strings.Repeat("a", 63) + " b", strings.Repeat("a", 63)},
+	}
+
+	for _, tt := range tests {
+		if got := Make(tt.in); got != tt.expected {
+			t.Errorf("Make(%q) = %q, expected %q", tt.in, got, tt.expected)
+		}
+	}
+}
+
+func TestIsWindowsReserved(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected bool
+	}{
+		{"nul.json", true},
+		{"COM3", true},
+		{"Aux.tar.gz", true},
+		{"console.json", false},
+		{"Dagger.json", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsWindowsReserved(tt.in); got != tt.expected {
+			t.Errorf("IsWindowsReserved(%q) = %v, expected %v", tt.in, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package sparse
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+// textureDefaults are the default values of the TextureData of the tokens.
+var textureDefaults = map[string]interface{}{
+	"anchorX":        0.5,
+	"anchorY":        0.5,
+	"offsetX":        0,
+	"offsetY":        0,
+	"fit":            "contain",
+	"scaleX":         1,
+	"scaleY":         1,
+	"rotation":       0,
+	"tint":           "#ffffff",
+	"alphaThreshold": 0.75,
+}
+
+// tokenDefaults are the default values of the fields shared by the tokens and the prototype tokens of the actors.
+var tokenDefaults = map[string]interface{}{
+	"displayName":      0,
+	"actorLink":        false,
+	"appendNumber":     false,
+	"prependAdjective": false,
+	"width":            1,
+	"height":           1,
+	"texture":          textureDefaults,
+	"hexagonalShape":   0,
+	"locked":           false,
+	"lockRotation":     false,
+	"rotation":         0,
+	"alpha":            1,
+	"disposition":      -1,
+	"displayBars":      0,
+	"light": map[string]interface{}{
+		"negative":    false,
+		"priority":    0,
+		"alpha":       0.5,
+		"angle":       360,
+		"bright":      0,
+		"color":       nil,
+		"coloration":  1,
+		"dim":         0,
+		"attenuation": 0.5,
+		"luminosity":  0.5,
+		"saturation":  0,
+		"contrast":    0,
+		"shadows":     0,
+		"animation": map[string]interface{}{
+			"type":      nil,
+			"speed":     5,
+			"intensity": 5,
+			"reverse":   false,
+		},
+		"darkness": map[string]interface{}{
+			"min": 0,
+			"max": 1,
+		},
+	},
+	"sight": map[string]interface{}{
+		"enabled":     false,
+		"range":       0,
+		"angle":       360,
+		"visionMode":  "basic",
+		"color":       nil,
+		"attenuation": 0.1,
+		"brightness":  0,
+		"saturation":  0,
+		"contrast":    0,
+	},
+	"detectionModes": []interface{}{},
+	"occludable": map[string]interface{}{
+		"radius": 0,
+	},
+	"ring": map[string]interface{}{
+		"enabled": false,
+		"colors": map[string]interface{}{
+			"ring":       nil,
+			"background": nil,
+		},
+		"effects": 1,
+		"subject": map[string]interface{}{
+			"scale":   1,
+			"texture": nil,
+		},
+	},
+	"flags": map[string]interface{}{},
+}
+
+// defaults are the default values of the fields of the documents, by collection. They are the core defaults of
+// Foundry v12, as written by unpack.
+var defaults = map[string]map[string]interface{}{
+	"actors": {
+		"prototypeToken": merge(tokenDefaults, map[string]interface{}{
+			"randomImg": false,
+		}),
+	},
+	"tokens": merge(tokenDefaults, map[string]interface{}{
+		"elevation": 0,
+		"hidden":    false,
+	}),
+	"scenes": {
+		"active":     false,
+		"navigation": true,
+		"navOrder":   0,
+		"navName":    "",
+		"background": merge(textureDefaults, map[string]interface{}{
+			"anchorX":        0,
+			"anchorY":        0,
+			"fit":            "fill",
+			"alphaThreshold": 0,
+		}),
+		"foreground": nil,
+		"grid": map[string]interface{}{
+			"type":      1,
+			"size":      100,
+			"style":     "solidLines",
+			"thickness": 1,
+			"color":     "#000000",
+			"alpha":     0.2,
+		},
+		"tokenVision": true,
+		"fog": map[string]interface{}{
+			"exploration": true,
+			"overlay":     nil,
+			"colors": map[string]interface{}{
+				"explored":   nil,
+				"unexplored": nil,
+			},
+		},
+		"environment": map[string]interface{}{
+			"darknessLevel": 0,
+			"darknessLock":  false,
+			"globalLight": map[string]interface{}{
+				"enabled": false,
+			},
+		},
+		"weather": "",
+	},
+}
+
+func merge(base map[string]interface{}, overrides map[string]interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+	for k, v := range base {
+		m[k] = v
+	}
+	for k, v := range overrides {
+		m[k] = v
+	}
+
+	return m
+}
+
+// Collection returns the collection of the documents of a LevelDB key, e.g. "items" for "!actors.items!a.b".
+func Collection(key string) string {
+	parts := strings.Split(key, "!")
+	if len(parts) < 3 {
+		return ""
+	}
+
+	return parts[1][strings.LastIndex(parts[1], ".")+1:]
+}
+
+// Strip removes the fields equal to their default value from the document v, decoded from JSON, and from its embedded
+// documents, recognized by their _key. Objects left empty are removed too.
+func Strip(v interface{}) {
+	docpath.EachObject(v, func(path string, obj map[string]interface{}) {
+		key, ok := obj["_key"].(string)
+		if !ok {
+			return
+		}
+		if d, ok := defaults[Collection(key)]; ok {
+			strip(obj, d)
+		}
+	})
+}
+
+func strip(obj map[string]interface{}, defaults map[string]interface{}) {
+	for k, d := range defaults {
+		v, ok := obj[k]
+		if !ok {
+			continue
+		}
+
+		if dm, ok := d.(map[string]interface{}); ok {
+			if vm, ok := v.(map[string]interface{}); ok {
+				strip(vm, dm)
+				if len(vm) == 0 {
+					delete(obj, k)
+				}
+			}
+			continue
+		}
+
+		if equal(v, d) {
+			delete(obj, k)
+		}
+	}
+}
+
+// Expand adds to a document of the given collection, as stored in the LevelDB, the fields it misses with their
+// default value.
+func Expand(doc map[string]interface{}, collection string) {
+	if d, ok := defaults[collection]; ok {
+		expand(doc, d)
+	}
+}
+
+func expand(obj map[string]interface{}, defaults map[string]interface{}) {
+	for k, d := range defaults {
+		v, ok := obj[k]
+		if dm, isMap := d.(map[string]interface{}); isMap {
+			if !ok {
+				v = map[string]interface{}{}
+				obj[k] = v
+			}
+			if vm, ok := v.(map[string]interface{}); ok {
+				expand(vm, dm)
+			}
+			continue
+		}
+
+		if !ok {
+			obj[k] = clone(d)
+		}
+	}
+}
+
+func clone(v interface{}) interface{} {
+	if l, ok := v.([]interface{}); ok {
+		return append([]interface{}{}, l...)
+	}
+
+	return v
+}
+
+// equal reports whether a value decoded from JSON equals a default value, numbers being compared by value.
+func equal(v interface{}, d interface{}) bool {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return false
+		}
+		switch dn := d.(type) {
+		case int:
+			return f == float64(dn)
+		case float64:
+			return f == dn
+		default:
+			return false
+		}
+	}
+
+	return reflect.DeepEqual(v, d)
+}
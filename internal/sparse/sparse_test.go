@@ -0,0 +1,153 @@
+package sparse
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+)
+
+func TestStripAndExpand(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		stripped string
+		// embedded is whether the document has embedded documents, which Expand leaves to their own entries.
+		embedded bool
+	}{
+		{
+			name: "token with default fields",
+			doc: `{"_id": "t000000000000001", "_key": "!scenes.tokens!s000000000000001.t000000000000001", "name": "Ghoul",
+				"x": 1200, "hidden": true, "width": 2, "elevation": 0, "disposition": -1, "alpha": 1.0,
+				"light": {"color": null, "dim": 0, "bright": 20, "alpha": 0.5, "animation": {"type": null, "speed": 5}},
+				"sight": {"enabled": false, "color": null, "range": 0, "angle": 360, "visionMode": "basic"},
+				"detectionModes": [],
+				"ring": {"enabled": false, "colors": {"ring": null, "background": null}, "subject": {"texture": null, "scale": 1}},
+				"texture": {"src": "tokens/ghoul.webp", "scaleX": 1, "tint": "#ffffff", "anchorX": 0.5},
+				"flags": {}}`,
+			stripped: `{"_id":"t000000000000001","_key":"!scenes.tokens!s000000000000001.t000000000000001","hidden":true,` +
+				`"light":{"bright":20},"name":"Ghoul","texture":{"src":"tokens/ghoul.webp"},"width":2,"x":1200}`,
+		},
+		{
+			name: "token with non-default colors and detection modes",
+			doc: `{"_id": "t000000000000002", "_key": "!scenes.tokens!s000000000000001.t000000000000002",
+				"light": {"color": "#ff0000", "dim": 0},
+				"sight": {"enabled": true, "color": "#00ff00", "range": 0.0},
+				"detectionModes": [{"id": "basicSight", "enabled": true, "range": 30}],
+				"ring": {"enabled": true, "colors": {"ring": "#ffffff", "background": null}},
+				"disposition": 0, "displayName": 30}`,
+			stripped: `{"_id":"t000000000000002","_key":"!scenes.tokens!s000000000000001.t000000000000002",` +
+				`"detectionModes":[{"enabled":true,"id":"basicSight","range":30}],"displayName":30,"disposition":0,` +
+				`"light":{"color":"#ff0000"},"ring":{"colors":{"ring":"#ffffff"},"enabled":true},` +
+				`"sight":{"color":"#00ff00","enabled":true}}`,
+		},
+		{
+			name: "scene with a token",
+			doc: `{"_id": "s000000000000001", "_key": "!scenes!s000000000000001", "name": "Crypt", "active": false,
+				"navigation": true, "foreground": null, "weather": "", "tokenVision": true,
+				"fog": {"exploration": true, "overlay": null, "colors": {"explored": null, "unexplored": "#000000"}},
+				"environment": {"darknessLevel": 0.5, "darknessLock": false, "globalLight": {"enabled": false}},
+				"grid": {"size": 100, "type": 1, "alpha": 0.2, "color": "#ff00ff"},
+				"tokens": [{"_id": "t000000000000001", "_key": "!scenes.tokens!s000000000000001.t000000000000001",
+					"x": 5, "hidden": false, "light": {"color": null}, "detectionModes": []}]}`,
+			stripped: `{"_id":"s000000000000001","_key":"!scenes!s000000000000001",` +
+				`"environment":{"darknessLevel":0.5},"fog":{"colors":{"unexplored":"#000000"}},` +
+				`"grid":{"color":"#ff00ff"},"name":"Crypt",` +
+				`"tokens":[{"_id":"t000000000000001","_key":"!scenes.tokens!s000000000000001.t000000000000001","x":5}]}`,
+			embedded: true,
+		},
+		{
+			name: "prototype token of an actor",
+			doc: `{"_id": "a000000000000001", "_key": "!actors!a000000000000001", "name": "Goblin",
+				"prototypeToken": {"name": "Goblin", "randomImg": false, "actorLink": true, "sight": {"enabled": true},
+					"light": {"color": null}, "detectionModes": []}}`,
+			stripped: `{"_id":"a000000000000001","_key":"!actors!a000000000000001","name":"Goblin",` +
+				`"prototypeToken":{"actorLink":true,"name":"Goblin","sight":{"enabled":true}}}`,
+		},
+		{
+			name:     "item without defaults",
+			doc:      `{"_id": "i000000000000001", "_key": "!items!i000000000000001", "name": "Sword", "flags": {}, "sort": 0}`,
+			stripped: `{"_id":"i000000000000001","_key":"!items!i000000000000001","flags":{},"name":"Sword","sort":0}`,
+		},
+	}
+
+	for _, tt := range tests {
+		original, err := docpath.Decode([]byte(tt.doc))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		v, _ := docpath.Decode([]byte(tt.doc))
+		Strip(v)
+		if got, _ := json.Marshal(v); string(got) != tt.stripped {
+			t.Errorf("%s: Strip gives %s, expected %s", tt.name, got, tt.stripped)
+		}
+		if tt.embedded {
+			continue
+		}
+
+		// Expanding the stripped document gives the original one with every default filled in.
+		doc := v.(map[string]interface{})
+		collection := Collection(doc["_key"].(string))
+		Expand(doc, collection)
+		Expand(original.(map[string]interface{}), collection)
+		got, expected := numbersByValue(doc), numbersByValue(original)
+		if got != expected {
+			t.Errorf("%s: Expand gives %s, expected %s", tt.name, got, expected)
+		}
+	}
+}
+
+// numbersByValue returns v as JSON, numbers being written by value, e.g. 1 for 1.0.
+func numbersByValue(v interface{}) string {
+	data, _ := json.Marshal(v)
+	var generic interface{}
+	_ = json.Unmarshal(data, &generic)
+	data, _ = json.Marshal(generic)
+
+	return string(data)
+}
+
+func TestExpandDoesNotShareDefaults(t *testing.T) {
+	a := map[string]interface{}{}
+	b := map[string]interface{}{}
+	Expand(a, "tokens")
+	Expand(b, "tokens")
+
+	a["detectionModes"] = append(a["detectionModes"].([]interface{}), "basicSight")
+	a["light"].(map[string]interface{})["dim"] = 10
+	if len(b["detectionModes"].([]interface{})) != 0 || b["light"].(map[string]interface{})["dim"] != 0 {
+		t.Errorf("expanded documents share their defaults: %v", b)
+	}
+	if _, ok := b["light"].(map[string]interface{})["color"]; !ok {
+		t.Errorf("light.color is not expanded: %v", b["light"])
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		def      interface{}
+		expected bool
+	}{
+		{json.Number("1"), 1, true},
+		{json.Number("1.0"), 1, true},
+		{json.Number("0.5"), 0.5, true},
+		{json.Number("-1"), -1, true},
+		{json.Number("2"), 1, false},
+		{json.Number("0.75"), 0.5, false},
+		{json.Number("1"), "1", false},
+		{json.Number("x"), 1, false},
+		{nil, nil, true},
+		{"#ffffff", "#ffffff", true},
+		{"", nil, false},
+		{false, false, true},
+		{[]interface{}{}, []interface{}{}, true},
+		{[]interface{}{"basicSight"}, []interface{}{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := equal(tt.value, tt.def); got != tt.expected {
+			t.Errorf("equal(%#v, %#v) = %t, expected %t", tt.value, tt.def, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package sqlexport
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/djlechuck/fvtt-packs/internal/docpath"
+	"github.com/djlechuck/fvtt-packs/internal/documents"
+	_ "modernc.org/sqlite"
+)
+
+// Columns are the columns promoted from the documents, which every table has, followed by the ids of the parents of
+// embedded documents and by DataColumn.
+var Columns = []string{"pack", "_id", "name", "type", "folder", "img"}
+
+// DataColumn is the column holding the whole document as JSON.
+const DataColumn = "data"
+
+// Exporter writes the documents of packs into a SQLite database, one table per collection, e.g. "actors" for the
+// actors and "actors_items" for their items. The tables of embedded documents have a column for the id of each of
+// their parents, e.g. "actor_id", referencing their table.
+type Exporter struct {
+	db     *sql.DB
+	tx     *sql.Tx
+	tables map[string]*sql.Stmt
+}
+
+// Create creates the database, replacing the file if it exists.
+func Create(file string) (*Exporter, error) {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", file)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Exporter{db: db, tx: tx, tables: map[string]*sql.Stmt{}}, nil
+}
+
+// Add inserts the LevelDB entry of the given key and value, decoded from JSON, found in the given pack.
+func (e *Exporter) Add(pack string, key string, v interface{}) error {
+	parts := strings.Split(key, "!")
+	if len(parts) < 3 {
+		return nil
+	}
+	collections := strings.Split(parts[1], ".")
+	ids := strings.Split(parts[2], ".")
+	if len(collections) != len(ids) {
+		return fmt.Errorf("invalid key %s\n", key)
+	}
+
+	data, err := docpath.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	insert, err := e.table(collections)
+	if err != nil {
+		return err
+	}
+
+	values := []interface{}{pack, ids[len(ids)-1]}
+	for _, c := range Columns[2:] {
+		value, _ := docpath.Get(v, c)
+		if s, ok := value.(string); ok && s != "" {
+			values = append(values, s)
+		} else {
+			values = append(values, nil)
+		}
+	}
+	for _, id := range ids[:len(ids)-1] {
+		values = append(values, id)
+	}
+	values = append(values, string(data))
+
+	_, err = insert.Exec(values...)
+
+	return err
+}
+
+// Close commits the database and closes it.
+func (e *Exporter) Close() error {
+	for _, stmt := range e.tables {
+		stmt.Close()
+	}
+	err := e.tx.Commit()
+	if closeErr := e.db.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Abort closes the database without committing it.
+func (e *Exporter) Abort() {
+	e.tx.Rollback()
+	e.db.Close()
+}
+
+// table creates the table of the given collection path, e.g. ["actors", "items"], if needed, and returns the statement
+// inserting into it.
+func (e *Exporter) table(collections []string) (*sql.Stmt, error) {
+	name := TableName(collections)
+	if stmt, ok := e.tables[name]; ok {
+		return stmt, nil
+	}
+
+	var columns, definitions []string
+	for _, c := range Columns {
+		columns = append(columns, quote(c))
+		definitions = append(definitions, quote(c)+" TEXT")
+	}
+	var parentColumns []string
+	for i := range collections[:len(collections)-1] {
+		c := quote(ParentColumn(collections[i]))
+		columns = append(columns, c)
+		definitions = append(definitions, c+" TEXT NOT NULL")
+		parentColumns = append(parentColumns, c)
+	}
+	columns = append(columns, quote(DataColumn))
+	definitions = append(definitions, quote(DataColumn)+" TEXT NOT NULL")
+
+	key := append(append([]string{quote("pack")}, parentColumns...), quote("_id"))
+	definitions = append(definitions, "PRIMARY KEY ("+strings.Join(key, ", ")+")")
+	if len(parentColumns) > 0 {
+		// The parent is identified by its own parents, then its id.
+		parent := collections[:len(collections)-1]
+		references := append(append([]string{quote("pack")}, parentColumns[:len(parentColumns)-1]...), quote("_id"))
+		definitions = append(definitions, fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+			strings.Join(key[:len(key)-1], ", "), quote(TableName(parent)), strings.Join(references, ", ")))
+	}
+
+	if _, err := e.tx.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", quote(name), strings.Join(definitions, ", "))); err != nil {
+		return nil, fmt.Errorf("cannot create table %s: %s\n", name, err)
+	}
+	for _, c := range []string{"name", "type"} {
+		index := quote(name + "_" + c)
+		if _, err := e.tx.Exec(fmt.Sprintf("CREATE INDEX %s ON %s (%s)", index, quote(name), quote(c))); err != nil {
+			return nil, fmt.Errorf("cannot create index of table %s: %s\n", name, err)
+		}
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	stmt, err := e.tx.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quote(name), strings.Join(columns, ", "), placeholders))
+	if err != nil {
+		return nil, err
+	}
+	e.tables[name] = stmt
+
+	return stmt, nil
+}
+
+// TableName returns the name of the table of a collection path, e.g. "actors_items" for ["actors", "items"].
+func TableName(collections []string) string {
+	return strings.Join(collections, "_")
+}
+
+// ParentColumn returns the column holding the id of the parent of an embedded document, e.g. "actor_id" for the
+// "actors" collection.
+func ParentColumn(collection string) string {
+	name, ok := documents.DocumentName(collection)
+	if !ok {
+		return collection + "_id"
+	}
+
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteRune('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String() + "_id"
+}
+
+func quote(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}